@@ -9,13 +9,15 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/kuznetsovin/egts-protocol/libs/egts"
 	"github.com/labstack/gommon/log"
 )
 
 type settings struct {
-	Srv   service
-	Store map[string]string
-	Log   logSection
+	Srv    service
+	Store  map[string]string
+	Log    logSection
+	Output outputSection
 }
 
 func (c *settings) Load(confPath string) error {
@@ -45,9 +47,11 @@ type store struct {
 }
 
 type service struct {
-	Host       string
-	Port       string
-	ConLiveSec int `toml:"con_live_sec"`
+	Host            string
+	Port            string
+	ConLiveSec      int   `toml:"con_live_sec"`
+	NoDelay         *bool `toml:"no_delay"`
+	WriteBufferSize int   `toml:"write_buffer_size"`
 }
 
 func (s *service) getEmptyConnTTL() time.Duration {
@@ -57,6 +61,18 @@ func (s *service) getServerAddress() string {
 	return s.Host + ":" + s.Port
 }
 
+type outputSection struct {
+	FloatPrecision *int `toml:"float_precision"`
+}
+
+// round округляет вещественное значение для выгрузки, если задано количество знаков после запятой
+func (o *outputSection) round(v float64) float64 {
+	if o.FloatPrecision == nil {
+		return v
+	}
+	return egts.RoundFloat(v, *o.FloatPrecision)
+}
+
 type logSection struct {
 	Level string
 }
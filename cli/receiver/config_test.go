@@ -56,3 +56,12 @@ level = "DEBUG"`
 		)
 	}
 }
+
+func TestOutputSectionRound(t *testing.T) {
+	o := outputSection{}
+	assert.Equal(t, 55.74312345, o.round(55.74312345))
+
+	precision := 5
+	o.FloatPrecision = &precision
+	assert.Equal(t, 55.74312, o.round(55.74312345))
+}
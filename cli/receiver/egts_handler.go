@@ -146,10 +146,16 @@ func handleRecvPkg(conn net.Conn, store Connector) {
 
 						exportPacket.NavigationTimestamp = subRecData.NavigationTime.Unix()
 						exportPacket.ReceivedTimestamp = receivedTimestamp
-						exportPacket.Latitude = subRecData.Latitude
-						exportPacket.Longitude = subRecData.Longitude
+						exportPacket.Latitude = config.Output.round(subRecData.Latitude)
+						exportPacket.Longitude = config.Output.round(subRecData.Longitude)
 						exportPacket.Speed = subRecData.Speed
 						exportPacket.Course = subRecData.Direction
+						// данные из черного ящика подтверждаются как обычные, чтобы терминал удалил их из буфера,
+						// но помечаются для низкоприоритетной обработки на стороне хранилища
+						exportPacket.Buffered = subRecData.IsBuffered()
+						if exportPacket.Buffered {
+							logger.Debugf("Получены данные из черного ящика: %s", subRecData.NavigationTime)
+						}
 						exportPacket.GUID = uuid.NewV4()
 					case *egts.SrExtPosData:
 						logger.Debugf("Разбор подзаписи EGTS_SR_EXT_POS_DATA")
@@ -61,12 +61,53 @@ func runServer(srvAddress string, store Connector) {
 	defer l.Close()
 
 	logger.Infof("Запущен сервер %s...", srvAddress)
+	if err = serve(l, store); err != nil {
+		logger.Errorf("Сервер остановлен: %v", err)
+	}
+}
+
+// serve принимает соединения на l, пока он не будет закрыт
+func serve(l net.Listener, store Connector) error {
 	for {
 		conn, err := l.Accept()
 		if err != nil {
-			logger.Errorf("Ошибка соединения: %v", err)
-		} else {
-			go handleRecvPkg(conn, store)
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				logger.Errorf("Ошибка соединения: %v", err)
+				continue
+			}
+			return err
+		}
+
+		if err := applyConnOptions(conn, config.Srv); err != nil {
+			logger.Warnf("Не удалось установить параметры соединения %s: %v", conn.RemoteAddr(), err)
+		}
+		go handleRecvPkg(conn, store)
+	}
+}
+
+// tcpOptionsConn параметры tcp соединения, которые можно настроить через конфиг
+type tcpOptionsConn interface {
+	SetNoDelay(bool) error
+	SetWriteBuffer(int) error
+}
+
+// applyConnOptions применяет к принятому соединению параметры no_delay и write_buffer_size из секции srv
+func applyConnOptions(conn net.Conn, s service) error {
+	tcpConn, ok := conn.(tcpOptionsConn)
+	if !ok {
+		return nil
+	}
+
+	if s.NoDelay != nil {
+		if err := tcpConn.SetNoDelay(*s.NoDelay); err != nil {
+			return err
+		}
+	}
+
+	if s.WriteBufferSize > 0 {
+		if err := tcpConn.SetWriteBuffer(s.WriteBufferSize); err != nil {
+			return err
 		}
 	}
+	return nil
 }
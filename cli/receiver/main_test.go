@@ -2,17 +2,36 @@ package main
 
 import (
 	"github.com/stretchr/testify/assert"
+	"io"
 	"net"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/kuznetsovin/egts-protocol/libs/egts"
 	"github.com/labstack/gommon/log"
 )
 
-func TestServer(t *testing.T) {
+func TestMain(m *testing.M) {
 	logger = log.New("-")
+	os.Exit(m.Run())
+}
+
+// startTestServer запускает прием соединений на свободном порту. Порт открыт до возврата, поэтому
+// подключаться к l.Addr() можно сразу, закрытие l останавливает сервер
+func startTestServer(t *testing.T, store Connector) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		_ = serve(l, store)
+	}()
+	return l
+}
 
-	srv := "127.0.0.1:5020"
+func TestServer(t *testing.T) {
 	message := []byte{0x01, 0x00, 0x00, 0x0B, 0x00, 0xB1, 0x00, 0xE8, 0x04, 0x01, 0x4E, 0xA6, 0x00, 0xA1, 0x0A, 0x81, 0x34, 0xF6, 0xE9, 0x01,
 		0x02, 0x02, 0x10, 0x1A, 0x00, 0x4F, 0x5F, 0xE5, 0x10, 0x00, 0xBE, 0xCD, 0x9E, 0x80, 0x7F, 0x8B, 0x35, 0x93, 0x9B, 0x80, 0x2F, 0xF9, 0x80,
 		0x02, 0x01, 0x00, 0x92, 0x00, 0x00, 0x00, 0x00, 0x11, 0x06, 0x00, 0x0E, 0x46, 0x00, 0x00, 0x00, 0x0C, 0x12, 0x1C, 0x00, 0x01, 0x0F, 0xFF,
@@ -24,22 +43,107 @@ func TestServer(t *testing.T) {
 		0x19, 0x04, 0x00, 0x6E, 0x77, 0x2A, 0x04, 0x41, 0xF6}
 	response := []byte{0x01, 0x00, 0x00, 0x0B, 0x00, 0x10, 0x00, 0x01, 0x00, 0x00, 0x2E, 0xE8, 0x04, 0x00, 0x06, 0x00, 0x01, 0x00, 0x20, 0x02, 0x02,
 		0x00, 0x03, 0x00, 0xA1, 0x0A, 0x00, 0x5E, 0xB6}
-	store := defaultConnector{}
-	// запускаем сервер
-	go func() {
-		runServer(srv, store)
-	}()
+	l := startTestServer(t, defaultConnector{})
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	_, _ = conn.Write(message)
 
-	time.Sleep(500 * time.Microsecond)
-	conn, err := net.Dial("tcp", srv)
+	buf := make([]byte, 29)
+	_, err = io.ReadFull(conn, buf)
 	if assert.NoError(t, err) {
-		_ = conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
-		_, _ = conn.Write(message)
+		assert.Equal(t, response, buf)
+	}
+}
+
+type captureConnector struct {
+	saved chan *egtsParsePacket
+}
+
+func (c captureConnector) Init(cfg map[string]string) error {
+	return nil
+}
+
+func (c captureConnector) Save(msg interface{ ToBytes() ([]byte, error) }) error {
+	c.saved <- msg.(*egtsParsePacket)
+	return nil
+}
+
+func (c captureConnector) Close() error {
+	return nil
+}
 
-		buf := make([]byte, 29)
-		_, _ = conn.Read(buf)
+func TestServerBufferedData(t *testing.T) {
+	store := captureConnector{saved: make(chan *egtsParsePacket, 2)}
+	l := startTestServer(t, store)
+	defer l.Close()
 
-		assert.Equal(t, buf, response)
+	send := func(conn net.Conn, pid uint16, bb string) {
+		pos := egts.SrPosData{
+			NavigationTime: time.Date(2021, time.February, 20, 0, 30, 40, 0, time.UTC),
+			Latitude:       55.55,
+			Longitude:      37.43,
+			ALTE:           "0", LOHS: "0", LAHS: "0", MV: "0", BB: bb, CS: "0", FIX: "1", VLD: "1",
+			Odometer: []byte{0x00, 0x00, 0x00},
+		}
+		pkg := egts.NewAppdataPackage(pid, egts.ServiceDataSet{
+			egts.NewServiceDataRecord(pid, 1, egts.TeledataService, egts.RecordDataSet{{SubrecordData: &pos}}),
+		})
+		rawPkg, err := pkg.Encode()
+		if assert.NoError(t, err) {
+			_, _ = conn.Write(rawPkg)
+			_, _ = egts.ReadPackage(conn)
+		}
+	}
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if !assert.NoError(t, err) {
+		return
 	}
 	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	send(conn, 1, "0")
+	send(conn, 2, "1")
+
+	assert.False(t, (<-store.saved).Buffered)
+	assert.True(t, (<-store.saved).Buffered)
+}
+
+type fakeTCPConn struct {
+	net.Conn
+	noDelay     *bool
+	writeBuffer int
+}
+
+func (c *fakeTCPConn) SetNoDelay(noDelay bool) error {
+	c.noDelay = &noDelay
+	return nil
+}
+
+func (c *fakeTCPConn) SetWriteBuffer(bytes int) error {
+	c.writeBuffer = bytes
+	return nil
+}
+
+func TestApplyConnOptions(t *testing.T) {
+	conn := &fakeTCPConn{}
+	if assert.NoError(t, applyConnOptions(conn, service{})) {
+		assert.Nil(t, conn.noDelay)
+		assert.Equal(t, 0, conn.writeBuffer)
+	}
+
+	noDelay := false
+	if assert.NoError(t, applyConnOptions(conn, service{NoDelay: &noDelay, WriteBufferSize: 65536})) {
+		if assert.NotNil(t, conn.noDelay) {
+			assert.False(t, *conn.noDelay)
+		}
+		assert.Equal(t, 65536, conn.writeBuffer)
+	}
 }
@@ -20,6 +20,7 @@ type egtsParsePacket struct {
 	Nsat                uint8          `json:"nsat"`
 	Ns                  uint16         `json:"ns"`
 	Course              uint8          `json:"course"`
+	Buffered            bool           `json:"buffered"`
 	GUID                uuid.UUID      `json:"guid"`
 	AnSensors           []anSensor     `json:"an_sensors"`
 	LiquidSensors       []liquidSensor `json:"liquid_sensors"`
@@ -1,32 +1,20 @@
 package main
 
 import (
-	"sync/atomic"
-
 	"github.com/kuznetsovin/egts-protocol/libs/egts"
 )
 
 var (
-	pidCounter uint32
-	rnCounter  uint32
+	pidCounter = egts.NewPidCounter(1)
+	rnCounter  = egts.NewUint16Counter(1)
 )
 
 func getNextPid() uint16 {
-	if pidCounter < 65535 {
-		atomic.AddUint32(&pidCounter, 1)
-	} else {
-		pidCounter = 0
-	}
-	return uint16(atomic.LoadUint32(&pidCounter))
+	return pidCounter.Next()
 }
 
 func getNextRN() uint16 {
-	if rnCounter < 65535 {
-		atomic.AddUint32(&rnCounter, 1)
-	} else {
-		rnCounter = 0
-	}
-	return uint16(atomic.LoadUint32(&rnCounter))
+	return rnCounter.Next()
 }
 
 func createPtResponse(p *egts.Package, resultCode, serviceType uint8, srResponses egts.RecordDataSet) ([]byte, error) {
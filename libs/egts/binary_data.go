@@ -6,3 +6,8 @@ type BinaryData interface {
 	Encode() ([]byte, error)
 	Length() uint16
 }
+
+// appendUint32 дописывает значение в конец среза в порядке little-endian
+func appendUint32(dst []byte, v uint32) []byte {
+	return append(dst, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
@@ -0,0 +1,219 @@
+package egts
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+//NewServiceDataRecord формирует запись уровня поддержки услуг для объекта с идентификатором oid.
+//Сервис отправителя и получателя совпадают
+func NewServiceDataRecord(rn uint16, oid uint32, serviceType byte, rds RecordDataSet) ServiceDataRecord {
+	return ServiceDataRecord{
+		RecordLength:             rds.Length(),
+		RecordNumber:             rn,
+		SourceServiceOnDevice:    "1",
+		RecipientServiceOnDevice: "0",
+		Group:                    "0",
+		RecordProcessingPriority: "00",
+		TimeFieldExists:          "0",
+		EventIDFieldExists:       "0",
+		ObjectIDFieldExists:      "1",
+		ObjectIdentifier:         oid,
+		SourceServiceType:        serviceType,
+		RecipientServiceType:     serviceType,
+		RecordDataSet:            rds,
+	}
+}
+
+//NewAppdataPackage формирует пакет EGTS_PT_APPDATA с набором записей sds. Приоритет не задается,
+//при кодировании используется DefaultPriority
+func NewAppdataPackage(pid uint16, sds ServiceDataSet) *Package {
+	return &Package{
+		ProtocolVersion:   1,
+		SecurityKeyID:     0,
+		Prefix:            "00",
+		Route:             "0",
+		EncryptionAlg:     "00",
+		Compression:       "0",
+		HeaderLength:      DEFAULT_HEADER_LEN,
+		HeaderEncoding:    0,
+		PacketIdentifier:  pid,
+		PacketType:        PtAppdataPacket,
+		ServicesFrameData: &sds,
+	}
+}
+
+//NewEmergencyAppdataPackage формирует пакет EGTS_PT_APPDATA с наивысшим приоритетом для экстренных данных
+func NewEmergencyAppdataPackage(pid uint16, sds ServiceDataSet) *Package {
+	pkg := NewAppdataPackage(pid, sds)
+	pkg.Priority = PriorityHighest
+	return pkg
+}
+
+//WrapSubrecord формирует минимальный пакет EGTS_PT_APPDATA с одной записью сервиса serviceType, содержащей
+//только подзапись sr. Номер записи совпадает с идентификатором пакета, необязательные поля записи не передаются
+func WrapSubrecord(serviceType byte, sr BinaryData, pid uint16) *Package {
+	rec := NewServiceDataRecord(pid, 0, serviceType, RecordDataSet{RecordData{SubrecordData: sr}})
+	rec.ObjectIDFieldExists = "0"
+
+	return NewAppdataPackage(pid, ServiceDataSet{rec})
+}
+
+//NewResponsePackage формирует пакет EGTS_PT_RESPONSE с результатом обработки pr пакета с идентификатором rpid.
+//Подтверждения записей records необязательны и передаются в SDR пакета
+func NewResponsePackage(pid, rpid uint16, pr uint8, records ServiceDataSet) *Package {
+	resp := PtResponse{
+		ResponsePacketID: rpid,
+		ProcessingResult: pr,
+	}
+	if len(records) > 0 {
+		resp.SDR = &records
+	}
+
+	pkg := NewAppdataPackage(pid, nil)
+	pkg.PacketType = PtResponsePacket
+	pkg.ServicesFrameData = &resp
+	return pkg
+}
+
+// responsePID счетчик идентификаторов пакетов, формируемых BuildResponsePacket
+var responsePID PidCounter
+
+//BuildResponsePacket формирует пакет EGTS_PT_RESPONSE на принятый пакет receivedPID. Подтверждения записей
+//confirmations передаются подзаписями EGTS_SR_RECORD_RESPONSE одной записи сервиса EGTS_TELEDATA_SERVICE.
+//Идентификатор самого ответа берется из собственного счетчика и не зависит от RPID
+func BuildResponsePacket(receivedPID uint16, processingResult byte, confirmations []SrResponse) (*Package, error) {
+	var records ServiceDataSet
+	pid := responsePID.Next()
+
+	if len(confirmations) > 0 {
+		// каждое подтверждение занимает 3 байта заголовка подзаписи и 3 байта CRN и RST
+		if len(confirmations)*(3+3) > math.MaxUint16 {
+			return nil, fmt.Errorf("Слишком много подтверждений записей для одного ответа: %d", len(confirmations))
+		}
+
+		rds := make(RecordDataSet, 0, len(confirmations))
+		for i := range confirmations {
+			rds = append(rds, RecordData{SubrecordType: SrRecordResponseType, SubrecordData: &confirmations[i]})
+		}
+
+		rec := NewServiceDataRecord(pid, 0, TeledataService, rds)
+		rec.SourceServiceOnDevice = "0"
+		rec.ObjectIDFieldExists = "0"
+		records = ServiceDataSet{rec}
+	}
+
+	return NewResponsePackage(pid, receivedPID, processingResult, records), nil
+}
+
+//PacketAck подтверждение одного принятого пакета для пакетной отправки ответов
+type PacketAck struct {
+	PacketID         uint16
+	ProcessingResult uint8
+	Records          ServiceDataSet
+}
+
+//EncodeResponseBatch формирует подтверждения сразу нескольких принятых пакетов одним блоком байт для одной записи
+//в соединение. Пакет EGTS_PT_RESPONSE содержит ровно один RPID, поэтому объединить подтверждения разных
+//пакетов внутри одного транспортного пакета стандарт не позволяет: каждый пакет получает свой ответ с
+//идентификаторами начиная с firstPID, а экономия достигается за счет одной операции записи. Пакетировать ответы
+//допустимо, только если все подтверждаемые пакеты уже приняты и обработаны, и задержка не превышает
+//TL_RESPONSE_TO терминала, иначе он повторит отправку
+func EncodeResponseBatch(firstPID uint16, acks []PacketAck) ([]byte, error) {
+	var result []byte
+
+	for i, ack := range acks {
+		respBytes, err := NewResponsePackage(firstPID+uint16(i), ack.PacketID, ack.ProcessingResult, ack.Records).Encode()
+		if err != nil {
+			return nil, fmt.Errorf("Не удалось сформировать подтверждение пакета %d: %v", ack.PacketID, err)
+		}
+		result = append(result, respBytes...)
+	}
+
+	return result, nil
+}
+
+//NewIdentityWithPositionPackage формирует пакет EGTS_PT_APPDATA из двух записей: EGTS_SR_TERM_IDENTITY сервиса
+//EGTS_AUTH_SERVICE с номером rn и EGTS_SR_POS_DATA сервиса EGTS_TELEDATA_SERVICE с номером rn+1. Так терминал
+//передает учетные данные и первую отметку без ожидания ответа на авторизацию
+func NewIdentityWithPositionPackage(pid, rn uint16, identity *SrTermIdentity, pos *SrPosData) *Package {
+	authRec := NewServiceDataRecord(rn, identity.TerminalIdentifier, AuthService,
+		RecordDataSet{RecordData{SubrecordData: identity}})
+	posRec := NewServiceDataRecord(rn+1, identity.TerminalIdentifier, TeledataService,
+		RecordDataSet{RecordData{SubrecordData: pos}})
+
+	return NewAppdataPackage(pid, ServiceDataSet{authRec, posRec})
+}
+
+//NewSrPosData формирует подзапись EGTS_SR_POS_DATA с достоверной отметкой. Время навигации ntm передается
+//явно, а не берется из системных часов: это нужно для воспроизводимых тестов и терминалов с собственным временем.
+//Знак координат задает полушарие (LAHS, LOHS), скорость указывается в км/ч
+func NewSrPosData(ntm time.Time, lat, lon float64, speed uint16) *SrPosData {
+	pos := SrPosData{
+		NavigationTime: ntm,
+		Latitude:       math.Abs(lat),
+		Longitude:      math.Abs(lon),
+		ALTE:           "0",
+		LOHS:           "0",
+		LAHS:           "0",
+		MV:             "0",
+		BB:             "0",
+		CS:             "0",
+		FIX:            "0",
+		VLD:            "1",
+		Speed:          speed,
+		Odometer:       []byte{0x00, 0x00, 0x00},
+	}
+	if lat < 0 {
+		pos.LAHS = "1"
+	}
+	if lon < 0 {
+		pos.LOHS = "1"
+	}
+	return &pos
+}
+
+//NewTelematicsPackage формирует пакет EGTS_PT_APPDATA с одной записью EGTS_TELEDATA_SERVICE объекта oid,
+//содержащей отметку на момент ntm. Номер записи совпадает с идентификатором пакета
+func NewTelematicsPackage(pid uint16, oid uint32, ntm time.Time, lat, lon float64, speed uint16) *Package {
+	rec := NewServiceDataRecord(pid, oid, TeledataService,
+		RecordDataSet{RecordData{SubrecordData: NewSrPosData(ntm, lat, lon, speed)}})
+
+	return NewAppdataPackage(pid, ServiceDataSet{rec})
+}
+
+//NewStatePackage формирует пакет EGTS_PT_APPDATA с одной записью EGTS_TELEDATA_SERVICE объекта oid, в которой
+//отметка pos передается вместе с состоянием терминала state (EGTS_SR_STATE_DATA). Если pos равен nil, запись
+//содержит только состояние, как в пакетах контроля связи. Номер записи совпадает с идентификатором пакета
+func NewStatePackage(pid uint16, oid uint32, pos *SrPosData, state *SrStateData) *Package {
+	rds := RecordDataSet{}
+	if pos != nil {
+		rds = append(rds, RecordData{SubrecordData: pos})
+	}
+	rds = append(rds, RecordData{SubrecordData: state})
+
+	return NewAppdataPackage(pid, ServiceDataSet{NewServiceDataRecord(pid, oid, TeledataService, rds)})
+}
+
+//BuildAuthResponse формирует пакет EGTS_PT_APPDATA с результатом авторизации терминала rcd (EGTS_SR_RESULT_CODE),
+//который платформа отправляет после обработки EGTS_SR_TERM_IDENTITY. Запись сервиса EGTS_AUTH_SERVICE
+//получает номер, совпадающий с идентификатором пакета pid. Если задан dispatcher, за результатом следует
+//подзапись EGTS_SR_DISPATCHER_IDENTITY с учетными данными платформы, nil - не передавать
+func BuildAuthResponse(pid uint16, rcd byte, dispatcher *SrDispatcherIdentity) (*Package, error) {
+	rds := RecordDataSet{
+		RecordData{SubrecordType: SrResultCodeType, SubrecordData: &SrResultCode{ResultCode: rcd}},
+	}
+	if dispatcher != nil {
+		rds = append(rds, RecordData{SubrecordType: SrDispatcherIdentityType, SubrecordData: dispatcher})
+	}
+
+	rec := NewServiceDataRecord(pid, 0, AuthService, rds)
+	rec.SourceServiceOnDevice = "0"
+	rec.Group = "1"
+	rec.ObjectIDFieldExists = "0"
+
+	pkg := NewAppdataPackage(pid, ServiceDataSet{rec})
+	pkg.Priority = PriorityHighest
+	return pkg, nil
+}
@@ -0,0 +1,249 @@
+package egts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestNewAppdataPackage_MultipleObjects(t *testing.T) {
+	truckPos := testEgtsSrPosData
+	trailerPos := testEgtsSrPosData
+	trailerPos.Speed = 0
+
+	pkg := NewAppdataPackage(1, ServiceDataSet{
+		NewServiceDataRecord(1, 1001, TeledataService, RecordDataSet{RecordData{SubrecordData: &truckPos}}),
+		NewServiceDataRecord(2, 1002, TeledataService, RecordDataSet{RecordData{SubrecordData: &trailerPos}}),
+	})
+
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	decodedPkg := Package{}
+	if _, err = decodedPkg.Decode(pkgBytes); assert.NoError(t, err) {
+		records := *decodedPkg.ServicesFrameData.(*ServiceDataSet)
+		if assert.Len(t, records, 2) {
+			assert.Equal(t, uint32(1001), records[0].ObjectIdentifier)
+			assert.Equal(t, uint32(1002), records[1].ObjectIdentifier)
+			assert.Equal(t, uint16(200), records[0].RecordDataSet[0].SubrecordData.(*SrPosData).Speed)
+			assert.Equal(t, uint16(0), records[1].RecordDataSet[0].SubrecordData.(*SrPosData).Speed)
+		}
+	}
+}
+
+func TestWrapSubrecord(t *testing.T) {
+	posData := testEgtsSrPosData
+	pkg := WrapSubrecord(TeledataService, &posData, 7)
+
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	// заголовок + заголовок записи без OID/EVID/TM + заголовок подзаписи + подзапись + SFRCS
+	assert.Len(t, pkgBytes, DEFAULT_HEADER_LEN+7+3+len(testEgtsSrPosDataBytes)+2)
+
+	decodedPkg := Package{}
+	if _, err = decodedPkg.Decode(pkgBytes); assert.NoError(t, err) {
+		records := *decodedPkg.ServicesFrameData.(*ServiceDataSet)
+		if assert.Len(t, records, 1) && assert.Len(t, records[0].RecordDataSet, 1) {
+			assert.Equal(t, uint16(7), records[0].RecordNumber)
+			assert.Equal(t, byte(TeledataService), records[0].SourceServiceType)
+			assert.Equal(t, byte(SrPosDataType), records[0].RecordDataSet[0].SubrecordType)
+			assert.Equal(t, &posData, records[0].RecordDataSet[0].SubrecordData)
+		}
+	}
+}
+
+func TestEncodeResponseBatch(t *testing.T) {
+	batch, err := EncodeResponseBatch(100, []PacketAck{
+		{PacketID: 1, ProcessingResult: egtsPcOk},
+		{PacketID: 2, ProcessingResult: egtsPcOk},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	r := bytes.NewReader(batch)
+	for i, rpid := range []uint16{1, 2} {
+		rawPkg, err := ReadPackage(r)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		pkg := Package{}
+		if _, err = pkg.Decode(rawPkg); assert.NoError(t, err) {
+			assert.Equal(t, uint16(100+i), pkg.PacketIdentifier)
+			assert.Equal(t, byte(PtResponsePacket), pkg.PacketType)
+			assert.Equal(t, rpid, pkg.ServicesFrameData.(*PtResponse).ResponsePacketID)
+		}
+	}
+
+	_, err = ReadPackage(r)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestNewIdentityWithPositionPackage(t *testing.T) {
+	identity := testEgtsSrTermIdentity
+	pos := testEgtsSrPosData
+
+	pkgBytes, err := NewIdentityWithPositionPackage(5, 10, &identity, &pos).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	decodedPkg := Package{}
+	if _, err = decodedPkg.Decode(pkgBytes); !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, uint16(5), decodedPkg.PacketIdentifier)
+	assert.Equal(t, uint16(len(pkgBytes)-DEFAULT_HEADER_LEN-2), decodedPkg.FrameDataLength)
+
+	records := *decodedPkg.ServicesFrameData.(*ServiceDataSet)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, uint16(10), records[0].RecordNumber)
+		assert.Equal(t, byte(AuthService), records[0].SourceServiceType)
+		assert.Equal(t, &identity, records[0].RecordDataSet[0].SubrecordData)
+
+		assert.Equal(t, uint16(11), records[1].RecordNumber)
+		assert.Equal(t, byte(TeledataService), records[1].SourceServiceType)
+		assert.Equal(t, uint32(133552), records[1].ObjectIdentifier)
+		assert.Equal(t, &pos, records[1].RecordDataSet[0].SubrecordData)
+	}
+}
+
+func TestNewAppdataPackage_Priority(t *testing.T) {
+	pos := testEgtsSrPosData
+	rec := NewServiceDataRecord(1, 1, TeledataService, RecordDataSet{RecordData{SubrecordData: &pos}})
+
+	for _, c := range []struct {
+		pkg      *Package
+		priority string
+	}{
+		{NewAppdataPackage(1, ServiceDataSet{rec}), PriorityLow},
+		{NewEmergencyAppdataPackage(2, ServiceDataSet{rec}), PriorityHighest},
+	} {
+		pkgBytes, err := c.pkg.Encode()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		decodedPkg := Package{}
+		if _, err = decodedPkg.Decode(pkgBytes); assert.NoError(t, err) {
+			assert.Equal(t, c.priority, decodedPkg.Priority)
+		}
+	}
+}
+
+func TestNewTelematicsPackage_FixedTime(t *testing.T) {
+	ntm := time.Date(2018, time.July, 6, 20, 8, 53, 0, time.UTC)
+
+	pkgBytes, err := NewTelematicsPackage(3, 1001, ntm, -55.5, 37.25, 60).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	decodedPkg := Package{}
+	if _, err = decodedPkg.Decode(pkgBytes); !assert.NoError(t, err) {
+		return
+	}
+
+	rec := (*decodedPkg.ServicesFrameData.(*ServiceDataSet))[0]
+	pos := rec.RecordDataSet[0].SubrecordData.(*SrPosData)
+	assert.Equal(t, ntm, pos.NavigationTime)
+
+	posBytes, err := pos.Encode()
+	if assert.NoError(t, err) {
+		// NTM - секунды с 01.01.2010 UTC
+		assert.Equal(t, testEgtsSrPosDataBytes[:4], posBytes[:4])
+	}
+	assert.Equal(t, "1", pos.LAHS)
+	assert.Equal(t, "0", pos.LOHS)
+	assert.Equal(t, uint16(60), pos.Speed)
+	assert.Equal(t, uint32(1001), rec.ObjectIdentifier)
+}
+
+func TestBuildResponsePacket(t *testing.T) {
+	pos := testEgtsSrPosData
+	received := WrapSubrecord(TeledataService, &pos, 0x0102)
+	receivedRec := (*received.ServicesFrameData.(*ServiceDataSet))[0]
+
+	pkg, err := BuildResponsePacket(received.PacketIdentifier, egtsPcOk, []SrResponse{
+		{ConfirmedRecordNumber: receivedRec.RecordNumber, RecordStatus: egtsPcOk},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	decoded := Package{}
+	if _, err = decoded.Decode(pkgBytes); !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, byte(PtResponsePacket), decoded.PacketType)
+
+	resp := decoded.ServicesFrameData.(*PtResponse)
+	assert.Equal(t, uint16(0x0102), resp.ResponsePacketID)
+	assert.Equal(t, egtsPcOk, resp.ProcessingResult)
+	if sds := resp.SDR.(*ServiceDataSet); assert.Len(t, *sds, 1) {
+		rec := (*sds)[0]
+		assert.Equal(t, byte(TeledataService), rec.SourceServiceType)
+		if assert.Len(t, rec.RecordDataSet, 1) {
+			assert.Equal(t, byte(SrRecordResponseType), rec.RecordDataSet[0].SubrecordType)
+			assert.Equal(t, &SrResponse{ConfirmedRecordNumber: receivedRec.RecordNumber, RecordStatus: egtsPcOk},
+				rec.RecordDataSet[0].SubrecordData)
+		}
+	}
+
+	// идентификатор ответа не повторяет RPID и меняется от ответа к ответу
+	next, err := BuildResponsePacket(received.PacketIdentifier, egtsPcOk, nil)
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, pkg.PacketIdentifier, next.PacketIdentifier)
+		assert.Nil(t, next.ServicesFrameData.(*PtResponse).SDR)
+	}
+}
+
+func TestNewStatePackage(t *testing.T) {
+	state := SrStateData{State: 2, MainPowerSourceVoltage: 127, BackUpBatteryVoltage: 41, InternalBatteryVoltage: 38,
+		NMS: "1", IBU: "1", BBU: "0"}
+	pos := testEgtsSrPosData
+
+	pkgBytes, err := NewStatePackage(5, 133552, &pos, &state).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	pkg := Package{}
+	if _, err = pkg.Decode(pkgBytes); !assert.NoError(t, err) {
+		return
+	}
+	rec := (*pkg.ServicesFrameData.(*ServiceDataSet))[0]
+	assert.Equal(t, byte(TeledataService), rec.SourceServiceType)
+	assert.Equal(t, uint32(133552), rec.ObjectIdentifier)
+	if assert.Len(t, rec.RecordDataSet, 2) {
+		assert.Equal(t, byte(SrPosDataType), rec.RecordDataSet[0].SubrecordType)
+		assert.Equal(t, &pos, rec.RecordDataSet[0].SubrecordData)
+		assert.Equal(t, byte(SrStateDataType), rec.RecordDataSet[1].SubrecordType)
+		assert.Equal(t, &state, rec.RecordDataSet[1].SubrecordData)
+	}
+
+	// контроль связи: только состояние терминала
+	pkgBytes, err = NewStatePackage(6, 133552, nil, &state).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	pkg = Package{}
+	if _, err = pkg.Decode(pkgBytes); assert.NoError(t, err) {
+		rec = (*pkg.ServicesFrameData.(*ServiceDataSet))[0]
+		if assert.Len(t, rec.RecordDataSet, 1) {
+			assert.Equal(t, &state, rec.RecordDataSet[0].SubrecordData)
+		}
+	}
+}
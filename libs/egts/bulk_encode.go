@@ -0,0 +1,42 @@
+package egts
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// пул буферов для массового кодирования, чтобы не выделять память под каждую пачку подзаписей
+var bulkBufPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 4096)
+		return &buf
+	},
+}
+
+//EncodePosDataBulk кодирует набор подзаписей EGTS_SR_POS_DATA в секцию RD записи (SRT, SRL и SRD для каждой
+//подзаписи) и записывает результат в w. Все подзаписи кодируются в один растущий буфер, взятый из пула,
+//поэтому после прогрева кодирование не выделяет память
+func EncodePosDataBulk(w io.Writer, positions []SrPosData) error {
+	bufPtr := bulkBufPool.Get().(*[]byte)
+	defer bulkBufPool.Put(bufPtr)
+
+	var err error
+	buf := (*bufPtr)[:0]
+	for i := range positions {
+		srlPos := len(buf) + 1
+		buf = append(buf, SrPosDataType, 0x00, 0x00)
+
+		if buf, err = positions[i].AppendTo(buf); err != nil {
+			return fmt.Errorf("Не удалось закодировать подзапись pos_data %d: %v", i, err)
+		}
+
+		srl := len(buf) - srlPos - 2
+		buf[srlPos] = byte(srl)
+		buf[srlPos+1] = byte(srl >> 8)
+	}
+	*bufPtr = buf
+
+	_, err = w.Write(buf)
+	return err
+}
@@ -0,0 +1,58 @@
+package egts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func bulkTestPositions(n int) []SrPosData {
+	positions := make([]SrPosData, n)
+	for i := range positions {
+		positions[i] = testEgtsSrPosData
+		positions[i].Speed = uint16(i % 250)
+	}
+	return positions
+}
+
+func TestEncodePosDataBulk(t *testing.T) {
+	positions := bulkTestPositions(3)
+
+	rds := RecordDataSet{}
+	for i := range positions {
+		rds = append(rds, RecordData{SubrecordData: &positions[i]})
+	}
+	expected, err := rds.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	if assert.NoError(t, EncodePosDataBulk(buf, positions)) {
+		assert.Equal(t, expected, buf.Bytes())
+	}
+
+	// после прогрева пула кодирование не выделяет память. Детектор гонок очищает sync.Pool случайным образом,
+	// поэтому с ним выделение памяти не проверяется
+	if raceEnabled {
+		return
+	}
+	positions = bulkTestPositions(1000)
+	_ = EncodePosDataBulk(ioutil.Discard, positions)
+	allocs := testing.AllocsPerRun(10, func() {
+		_ = EncodePosDataBulk(ioutil.Discard, positions)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func BenchmarkEncodePosDataBulk(b *testing.B) {
+	positions := bulkTestPositions(1000)
+	_ = EncodePosDataBulk(ioutil.Discard, positions)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = EncodePosDataBulk(ioutil.Discard, positions)
+	}
+}
@@ -0,0 +1,67 @@
+package egts
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+//Cipher шифрует и расшифровывает секцию данных пакета (SFRD) ключом с идентификатором skid (поле SKID).
+//Переданные данные изменять нельзя: по ним считается контрольная сумма SFRCS
+type Cipher interface {
+	Encrypt(skid byte, data []byte) ([]byte, error)
+	Decrypt(skid byte, data []byte) ([]byte, error)
+}
+
+//NoopCipher шифрование по умолчанию для кодов ENA без зарегистрированного алгоритма: данные передаются как есть
+type NoopCipher struct{}
+
+//Encrypt возвращает данные без изменений
+func (NoopCipher) Encrypt(skid byte, data []byte) ([]byte, error) {
+	return data, nil
+}
+
+//Decrypt возвращает данные без изменений
+func (NoopCipher) Decrypt(skid byte, data []byte) ([]byte, error) {
+	return data, nil
+}
+
+// ciphers алгоритмы шифрования по коду ENA. Регистрация возможна во время разбора пакетов в других
+// горутинах, поэтому доступ защищен ciphersMu
+var (
+	ciphersMu sync.RWMutex
+	ciphers   = map[byte]Cipher{}
+)
+
+//RegisterCipher задает алгоритм шифрования для кода ENA (1-3), которым шифруется SFRD пакетов с этим кодом.
+//Спецификация не определяет сами алгоритмы, поэтому без регистрации используется NoopCipher, nil отменяет
+//регистрацию. Безопасна для вызова одновременно с разбором и кодированием пакетов
+func RegisterCipher(ena byte, c Cipher) {
+	ciphersMu.Lock()
+	defer ciphersMu.Unlock()
+
+	if c == nil {
+		delete(ciphers, ena)
+		return
+	}
+	ciphers[ena] = c
+}
+
+// cipher алгоритм шифрования пакета по полю ENA или nil, если SFRD не шифруется
+func (p *Package) cipher() (Cipher, error) {
+	ena, err := strconv.ParseUint(p.EncryptionAlg, 2, 2)
+	if err != nil {
+		return nil, fmt.Errorf("Некорректное значение поля ENA: %q", p.EncryptionAlg)
+	}
+	if ena == 0 {
+		return nil, nil
+	}
+
+	ciphersMu.RLock()
+	c, ok := ciphers[byte(ena)]
+	ciphersMu.RUnlock()
+	if ok {
+		return c, nil
+	}
+	return NoopCipher{}, nil
+}
@@ -0,0 +1,88 @@
+package egts
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+// xorCipher шифрование XOR с ключом, равным SKID
+type xorCipher struct{}
+
+func (xorCipher) Encrypt(skid byte, data []byte) ([]byte, error) {
+	result := make([]byte, len(data))
+	for i := range data {
+		result[i] = data[i] ^ skid
+	}
+	return result, nil
+}
+
+func (c xorCipher) Decrypt(skid byte, data []byte) ([]byte, error) {
+	return c.Encrypt(skid, data)
+}
+
+func TestPackage_Cipher(t *testing.T) {
+	RegisterCipher(1, xorCipher{})
+	defer RegisterCipher(1, nil)
+
+	pos := testEgtsSrPosData
+	pkg := NewTelematicsPackage(1, 133552, pos.NavigationTime, pos.Latitude, pos.Longitude, pos.Speed)
+	plainSFRD, err := pkg.ServicesFrameData.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	pkg.EncryptionAlg, pkg.SecurityKeyID = "01", 0x5A
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// на линии SFRD зашифрована, SFRCS считается по зашифрованным байтам
+	hl, fdl := int(pkgBytes[3]), int(binary.LittleEndian.Uint16(pkgBytes[5:7]))
+	sent := pkgBytes[hl : hl+fdl]
+	encrypted, _ := xorCipher{}.Encrypt(0x5A, plainSFRD)
+	assert.Equal(t, encrypted, sent)
+	assert.Equal(t, crc16(sent), binary.LittleEndian.Uint16(pkgBytes[hl+fdl:]))
+
+	decoded := Package{}
+	if _, err = decoded.Decode(pkgBytes); assert.NoError(t, err) {
+		assert.Equal(t, "01", decoded.EncryptionAlg)
+		decodedSFRD, err := decoded.ServicesFrameData.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, plainSFRD, decodedSFRD)
+		}
+	}
+
+	// без зарегистрированного алгоритма данные передаются как есть
+	pkg.EncryptionAlg = "10"
+	pkgBytes, err = pkg.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, plainSFRD, pkgBytes[hl:len(pkgBytes)-2])
+	}
+}
+
+func TestRegisterCipher_Concurrent(t *testing.T) {
+	defer RegisterCipher(1, nil)
+
+	// регистрация во время выбора алгоритма для пакетов в других горутинах (проверяется go test -race)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c, err := (&Package{EncryptionAlg: "01"}).cipher()
+				if assert.NoError(t, err) {
+					assert.NotNil(t, c)
+				}
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		RegisterCipher(1, xorCipher{})
+		RegisterCipher(1, nil)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,190 @@
+package egts
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// defaultResponseTimeout время ожидания ответа по умолчанию, соответствует TL_RESPONSE_TO
+const defaultResponseTimeout = 5 * time.Second
+
+//Client клиент, отправляющий пакеты ЕГТС на платформу по установленному соединению
+type Client struct {
+	// MaxOutstanding максимальное число отправленных, но еще не подтвержденных пакетов. 0 - без ограничения
+	MaxOutstanding int
+	// Timeout максимальное время ожидания очередного ответа платформы, по умолчанию TL_RESPONSE_TO (5 секунд).
+	// 0 - без ограничения
+	Timeout time.Duration
+	// PackageHandler получает принятые во время отправки пачки пакеты, которые не подтверждают ее пакеты,
+	// например результат авторизации EGTS_SR_RESULT_CODE или команды платформы. nil - такие пакеты отбрасываются
+	PackageHandler func(pkg *Package)
+
+	conn       net.Conn
+	dispatcher *SrDispatcherIdentity
+}
+
+//UnconfirmedError ошибка отправки пачки, после которой подтверждение пакетов PIDs не получено. Err - причина,
+//по которой отправка прервана
+type UnconfirmedError struct {
+	PIDs []uint16
+	Err  error
+}
+
+func (e *UnconfirmedError) Error() string {
+	return fmt.Sprintf("Не получено подтверждение пакетов %v: %v", e.PIDs, e.Err)
+}
+
+//Unwrap возвращает причину ошибки для errors.Is
+func (e *UnconfirmedError) Unwrap() error {
+	return e.Err
+}
+
+//NewClient создает клиента поверх соединения conn
+func NewClient(conn net.Conn) *Client {
+	return &Client{conn: conn, Timeout: defaultResponseTimeout}
+}
+
+//SendBatch отправляет пакеты, не дожидаясь подтверждения каждого, с учетом MaxOutstanding, и сопоставляет
+//полученные EGTS_PT_RESPONSE с отправленными пакетами по RPID. Возвращает ответы по идентификаторам пакетов.
+//Если отправка прервана или ответ не получен за Timeout, вместе с полученными ответами возвращается
+//*UnconfirmedError со списком неподтвержденных пакетов
+func (c *Client) SendBatch(pkgs []*Package) (map[uint16]*PtResponse, error) {
+	pending := make(map[uint16]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		if pending[pkg.PacketIdentifier] {
+			return nil, fmt.Errorf("Повторяющийся идентификатор пакета в пачке: %d", pkg.PacketIdentifier)
+		}
+		pending[pkg.PacketIdentifier] = true
+	}
+
+	window := c.MaxOutstanding
+	if window <= 0 || window > len(pkgs) {
+		window = len(pkgs)
+	}
+
+	var (
+		mu       sync.Mutex
+		writeErr error
+	)
+	done := make(chan struct{})
+	writerDone := make(chan struct{})
+	slots := make(chan struct{}, window)
+	go func() {
+		defer close(writerDone)
+		for _, pkg := range pkgs {
+			select {
+			case slots <- struct{}{}:
+			case <-done:
+				return
+			}
+
+			pkgBytes, err := pkg.Encode()
+			if err != nil {
+				err = fmt.Errorf("Не удалось сформировать пакет %d: %v", pkg.PacketIdentifier, err)
+			} else if _, err = c.conn.Write(pkgBytes); err != nil {
+				err = fmt.Errorf("Не удалось отправить пакет %d: %v", pkg.PacketIdentifier, err)
+			}
+			if err != nil {
+				// ответы на неотправленные пакеты не придут, поэтому ожидание ответа прерывается
+				mu.Lock()
+				writeErr = err
+				_ = c.conn.SetReadDeadline(time.Now())
+				mu.Unlock()
+				return
+			}
+		}
+	}()
+
+	// писатель завершается до выхода, чтобы не писать в соединение после возврата ошибки
+	defer func() {
+		close(done)
+		_ = c.conn.SetWriteDeadline(time.Now())
+		<-writerDone
+		_ = c.conn.SetDeadline(time.Time{})
+	}()
+
+	// ответы читаются до последнего ожидаемого подтверждения, чтобы не забирать из соединения ответы на
+	// следующую пачку
+	acks := make(map[uint16]*PtResponse, len(pkgs))
+	for len(pending) > 0 {
+		mu.Lock()
+		err := writeErr
+		if err == nil && c.Timeout > 0 {
+			err = c.conn.SetReadDeadline(time.Now().Add(c.Timeout))
+		}
+		mu.Unlock()
+		if err != nil {
+			return acks, unconfirmed(pkgs, acks, err)
+		}
+
+		rawPkg, err := ReadPackage(c.conn)
+		if err != nil {
+			mu.Lock()
+			if writeErr != nil {
+				err = writeErr
+			} else {
+				err = fmt.Errorf("Не удалось получить ответ: %v", err)
+			}
+			mu.Unlock()
+			return acks, unconfirmed(pkgs, acks, err)
+		}
+
+		pkg := &Package{}
+		if _, err = pkg.Decode(rawPkg); err != nil {
+			return acks, unconfirmed(pkgs, acks, fmt.Errorf("Не удалось разобрать ответ: %v", err))
+		}
+
+		resp, ok := pkg.ServicesFrameData.(*PtResponse)
+		if !ok || !pending[resp.ResponsePacketID] {
+			if c.PackageHandler != nil {
+				c.PackageHandler(pkg)
+			}
+			continue
+		}
+		delete(pending, resp.ResponsePacketID)
+
+		if dispatcher := dispatcherIdentity(resp); dispatcher != nil {
+			c.dispatcher = dispatcher
+		}
+		acks[resp.ResponsePacketID] = resp
+		<-slots
+	}
+
+	return acks, nil
+}
+
+// unconfirmed формирует ошибку со списком пакетов пачки, на которые не получено подтверждение
+func unconfirmed(pkgs []*Package, acks map[uint16]*PtResponse, err error) error {
+	pids := []uint16{}
+	for _, pkg := range pkgs {
+		if _, ok := acks[pkg.PacketIdentifier]; !ok {
+			pids = append(pids, pkg.PacketIdentifier)
+		}
+	}
+	return &UnconfirmedError{PIDs: pids, Err: err}
+}
+
+//DispatcherIdentity возвращает учетные данные платформы, переданные в подтверждении авторизации
+//(EGTS_SR_DISPATCHER_IDENTITY), или nil, если платформа их не передавала
+func (c *Client) DispatcherIdentity() *SrDispatcherIdentity {
+	return c.dispatcher
+}
+
+// dispatcherIdentity ищет подзапись EGTS_SR_DISPATCHER_IDENTITY в записях ответа
+func dispatcherIdentity(resp *PtResponse) *SrDispatcherIdentity {
+	sds, ok := resp.SDR.(*ServiceDataSet)
+	if !ok {
+		return nil
+	}
+
+	for _, rec := range *sds {
+		for _, subRec := range rec.RecordDataSet {
+			if dispatcher, ok := subRec.SubrecordData.(*SrDispatcherIdentity); ok {
+				return dispatcher
+			}
+		}
+	}
+	return nil
+}
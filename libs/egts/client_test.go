@@ -0,0 +1,110 @@
+package egts
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClient_SendBatch(t *testing.T) {
+	conn := startTestServer(&Server{
+		Handler: func(rec *ServiceDataRecord) (uint8, Directive) {
+			return egtsPcOk, Continue
+		},
+	})
+	defer conn.Close()
+
+	pkgs := []*Package{}
+	for i := 0; i < 5; i++ {
+		pos := testEgtsSrPosData
+		pkgs = append(pkgs, WrapSubrecord(TeledataService, &pos, uint16(10+i)))
+	}
+
+	client := NewClient(conn)
+	client.MaxOutstanding = 2
+	acks, err := client.SendBatch(pkgs)
+	if assert.NoError(t, err) && assert.Len(t, acks, 5) {
+		for i := 0; i < 5; i++ {
+			if resp, ok := acks[uint16(10+i)]; assert.True(t, ok) {
+				assert.Equal(t, egtsPcOk, resp.ProcessingResult)
+			}
+		}
+	}
+}
+
+func TestClient_DispatcherIdentity(t *testing.T) {
+	dispatcher := SrDispatcherIdentity{DispatcherType: 0, DispatcherID: 1024, Description: "platform"}
+	conn := startTestServer(&Server{RequireAuth: true, DispatcherIdentity: &dispatcher})
+	defer conn.Close()
+
+	client := NewClient(conn)
+	pos := testEgtsSrPosData
+	_, err := client.SendBatch([]*Package{WrapSubrecord(TeledataService, &pos, 1)})
+	if assert.NoError(t, err) {
+		assert.Nil(t, client.DispatcherIdentity())
+	}
+
+	identity := testEgtsSrTermIdentity
+	_, err = client.SendBatch([]*Package{WrapSubrecord(AuthService, &identity, 2)})
+	if assert.NoError(t, err) && assert.NotNil(t, client.DispatcherIdentity()) {
+		assert.Equal(t, uint32(1024), client.DispatcherIdentity().DispatcherID)
+		assert.Equal(t, "platform", client.DispatcherIdentity().Description)
+	}
+}
+
+func TestClient_SendBatchNoResponse(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	// платформа принимает пакеты, но не отвечает
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, serverConn)
+	}()
+	defer serverConn.Close()
+
+	pos := testEgtsSrPosData
+	client := NewClient(clientConn)
+	client.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	acks, err := client.SendBatch([]*Package{
+		WrapSubrecord(TeledataService, &pos, 1),
+		WrapSubrecord(TeledataService, &pos, 2),
+	})
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Empty(t, acks)
+
+	var unconfirmedErr *UnconfirmedError
+	if assert.True(t, errors.As(err, &unconfirmedErr)) {
+		assert.Equal(t, []uint16{1, 2}, unconfirmedErr.PIDs)
+	}
+}
+
+func TestClient_PackageHandler(t *testing.T) {
+	conn := startTestServer(&Server{RequireAuth: true})
+	defer conn.Close()
+
+	received := []*Package{}
+	client := NewClient(conn)
+	client.PackageHandler = func(pkg *Package) {
+		received = append(received, pkg)
+	}
+
+	identity := testEgtsSrTermIdentity
+	_, err := client.SendBatch([]*Package{WrapSubrecord(AuthService, &identity, 1)})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// результат авторизации приходит после подтверждения и передается обработчику при отправке следующей пачки
+	pos := testEgtsSrPosData
+	acks, err := client.SendBatch([]*Package{WrapSubrecord(TeledataService, &pos, 2)})
+	if assert.NoError(t, err) && assert.Len(t, received, 1) {
+		assert.Contains(t, acks, uint16(2))
+		rec := (*received[0].ServicesFrameData.(*ServiceDataSet))[0]
+		assert.Equal(t, egtsPcOk, rec.RecordDataSet[0].SubrecordData.(*SrResultCode).ResultCode)
+	}
+}
@@ -60,11 +60,35 @@ const PtAppdataPacket = 1
 //PtResponsePacket код типа пакета PT_RESPONSE
 const PtResponsePacket = 0
 
+//PtSignedAppdataPacket код типа пакета PT_SIGNED_APPDATA
+const PtSignedAppdataPacket = 2
+
 //AuthService тип сервиса AUTH_SERVICE
 const AuthService = 1
 
 //TeledataService тип сервиса TELEDATA_SERVICE
 const TeledataService = 2
 
+//CommandsService тип сервиса COMMANDS_SERVICE
+const CommandsService = 4
+
+//SrCommandDataType код типа подзаписи EGTS_SR_COMMAND_DATA
+const SrCommandDataType = 51
+
 //SrDispatcherIdentityType код типа подзаписи EGTS_SR_DISPATCHER_IDENTITY
 const SrDispatcherIdentityType = 5
+
+//PriorityHighest наивысший приоритет маршрутизации пакета (поле PR)
+const PriorityHighest = "00"
+
+//PriorityHigh высокий приоритет маршрутизации пакета
+const PriorityHigh = "01"
+
+//PriorityMedium средний приоритет маршрутизации пакета
+const PriorityMedium = "10"
+
+//PriorityLow низкий приоритет маршрутизации пакета
+const PriorityLow = "11"
+
+//DefaultPriority приоритет, с которым кодируется пакет, если поле PR не заполнено
+var DefaultPriority = PriorityLow
@@ -0,0 +1,29 @@
+package egts
+
+import "sync/atomic"
+
+//Uint16Counter потокобезопасный циклический счетчик от 0 до 65535, после 65535 снова начинается с 0.
+//Используется для идентификаторов пакетов (PID) и номеров записей (RN). Нулевое значение готово к работе
+//и начинает отсчет с 0
+type Uint16Counter struct {
+	next uint32
+}
+
+//PidCounter счетчик идентификаторов пакетов (PID)
+type PidCounter = Uint16Counter
+
+//NewUint16Counter создает счетчик, первый вызов Next которого вернет start
+func NewUint16Counter(start uint16) *Uint16Counter {
+	return &Uint16Counter{next: uint32(start)}
+}
+
+//NewPidCounter создает счетчик идентификаторов пакетов, начинающийся с start
+func NewPidCounter(start uint16) *PidCounter {
+	return NewUint16Counter(start)
+}
+
+//Next возвращает очередное значение счетчика
+func (c *Uint16Counter) Next() uint16 {
+	// 2^32 кратно 2^16, поэтому переполнение uint32 не нарушает цикличность младших 16 бит
+	return uint16(atomic.AddUint32(&c.next, 1) - 1)
+}
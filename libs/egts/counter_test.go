@@ -0,0 +1,46 @@
+package egts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestUint16Counter_Wraparound(t *testing.T) {
+	c := NewPidCounter(65534)
+	assert.Equal(t, uint16(65534), c.Next())
+	assert.Equal(t, uint16(65535), c.Next())
+	assert.Equal(t, uint16(0), c.Next())
+	assert.Equal(t, uint16(1), c.Next())
+
+	var zero Uint16Counter
+	assert.Equal(t, uint16(0), zero.Next())
+}
+
+func TestUint16Counter_Concurrent(t *testing.T) {
+	const workers, perWorker = 8, 8192
+
+	c := NewUint16Counter(65000)
+	results := make(chan uint16, workers*perWorker)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				results <- c.Next()
+			}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	// ровно один полный цикл: каждое значение встречается один раз
+	seen := make(map[uint16]bool, workers*perWorker)
+	for v := range results {
+		assert.False(t, seen[v], "повтор значения %d", v)
+		seen[v] = true
+	}
+	assert.Len(t, seen, 1<<16)
+}
@@ -1,5 +1,7 @@
 package egts
 
+import "fmt"
+
 func crc8(data []byte) byte {
 	crc := byte(0xFF)
 	for _, b := range data {
@@ -33,3 +35,18 @@ func crc16(data []byte) uint16 {
 
 	return crc
 }
+
+// headerCheckSum вычисляет HCS по уже сериализованному заголовку: CRC-8 от PRV до HCS, не включая его.
+// Длина заголовка берется из поля HL (4-й байт)
+func headerCheckSum(header []byte) (byte, error) {
+	if len(header) < 4 {
+		return 0, fmt.Errorf("Недостаточно данных для вычисления crc заголовка: %d байт", len(header))
+	}
+
+	hl := int(header[3])
+	if hl < 1 || len(header) < hl-1 {
+		return 0, fmt.Errorf("Длина заголовка %d байт меньше указанной в HL: %d", len(header), hl)
+	}
+
+	return crc8(header[:hl-1]), nil
+}
@@ -18,3 +18,34 @@ func Test_crc16(t *testing.T) {
 
 	assert.Equal(t, crc, checkVal)
 }
+
+func Test_headerCheckSum(t *testing.T) {
+	tests := []struct {
+		name string
+		pkg  []byte
+	}{
+		{"EGTS_PT_APPDATA с EGTS_SR_POS_DATA", egtsPkgPosDataBytes},
+		{"EGTS_PT_RESPONSE", testEgtsPkgBytes},
+		{"EGTS_PT_RESPONSE с маршрутизацией", goldenRoutedResponseBytes},
+		{"EGTS_SR_TERM_IDENTITY", testEgtsSrTermIdentityPkgBin},
+	}
+
+	for _, tt := range tests {
+		hl := tt.pkg[3]
+		hcs, err := headerCheckSum(tt.pkg[:hl-1])
+		if assert.NoError(t, err, tt.name) {
+			assert.Equal(t, tt.pkg[hl-1], hcs, tt.name)
+		}
+	}
+
+	_, err := headerCheckSum(goldenRoutedResponseBytes[:10])
+	assert.Error(t, err)
+}
+
+func TestPackage_DecodeHeaderLengthOverflow(t *testing.T) {
+	pkg := append([]byte{}, testEgtsPkgBytes[:11]...)
+	pkg[3] = 0xF0
+
+	_, err := (&Package{}).Decode(pkg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package egts
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// packetTypeNames названия типов пакетов по коду PT
+var packetTypeNames = map[byte]string{
+	PtResponsePacket:      "EGTS_PT_RESPONSE",
+	PtAppdataPacket:       "EGTS_PT_APPDATA",
+	PtSignedAppdataPacket: "EGTS_PT_SIGNED_APPDATA",
+}
+
+// serviceNames названия сервисов по коду SST/RST
+var serviceNames = map[byte]string{
+	AuthService:     "EGTS_AUTH_SERVICE",
+	TeledataService: "EGTS_TELEDATA_SERVICE",
+	CommandsService: "EGTS_COMMANDS_SERVICE",
+}
+
+// subrecordNames названия подзаписей по коду SRT
+var subrecordNames = map[byte]string{
+	SrRecordResponseType:     "EGTS_SR_RECORD_RESPONSE",
+	SrTermIdentityType:       "EGTS_SR_TERM_IDENTITY",
+	SrDispatcherIdentityType: "EGTS_SR_DISPATCHER_IDENTITY",
+	SrAuthInfoType:           "EGTS_SR_AUTH_INFO",
+	SrResultCodeType:         "EGTS_SR_RESULT_CODE",
+	SrEgtsPlusDataType:       "EGTS_SR_EGTS_PLUS_DATA",
+	SrPosDataType:            "EGTS_SR_POS_DATA",
+	SrExtPosDataType:         "EGTS_SR_EXT_POS_DATA",
+	SrAdSensorsDataType:      "EGTS_SR_AD_SENSORS_DATA",
+	SrCountersDataType:       "EGTS_SR_COUNTERS_DATA",
+	SrStateDataType:          "EGTS_SR_STATE_DATA",
+	SrLoopinDataType:         "EGTS_SR_LOOPIN_DATA",
+	SrAbsDigSensDataType:     "EGTS_SR_ABS_DIG_SENS_DATA",
+	SrAbsAnSensDataType:      "EGTS_SR_ABS_AN_SENS_DATA",
+	SrAbsCntrDataType:        "EGTS_SR_ABS_CNTR_DATA",
+	SrAbsLoopinDataType:      "EGTS_SR_ABS_LOOPIN_DATA",
+	SrLiquidLevelSensorType:  "EGTS_SR_LIQUID_LEVEL_SENSOR",
+	SrPassengersCountersType: "EGTS_SR_PASSENGERS_COUNTERS",
+	SrCommandDataType:        "EGTS_SR_COMMAND_DATA",
+}
+
+// codeName название кода из names или unknown(code)
+func codeName(names map[byte]string, code byte) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", code)
+}
+
+//HeaderString описывает заголовок пакета одной строкой: название типа пакета, идентификатор, длины и флаги,
+//для пакета с маршрутизацией - адреса и TTL
+func (p *Package) HeaderString() string {
+	priority := p.Priority
+	if priority == "" {
+		priority = DefaultPriority
+	}
+
+	header := fmt.Sprintf("%s PID=%d FDL=%d HL=%d PRV=%d SKID=%d PRF=%s RTE=%s ENA=%s CMP=%s PR=%s",
+		codeName(packetTypeNames, p.PacketType), p.PacketIdentifier, p.FrameDataLength, p.HeaderLength,
+		p.ProtocolVersion, p.SecurityKeyID, p.Prefix, p.Route, p.EncryptionAlg, p.Compression, priority)
+	if p.Route == "1" {
+		header += fmt.Sprintf(" PRA=%d RCA=%d TTL=%d", p.PeerAddress, p.RecipientAddress, p.TimeToLive)
+	}
+	return header
+}
+
+//String описывает пакет для отладки: заголовок (см. HeaderString), затем по строке на каждую запись и
+//подзапись. Для основных подзаписей выводятся их ключевые значения
+func (p *Package) String() string {
+	var sb strings.Builder
+	sb.WriteString(p.HeaderString())
+
+	var records BinaryData
+	switch frame := p.ServicesFrameData.(type) {
+	case *PtResponse:
+		fmt.Fprintf(&sb, "\n  RPID=%d PR=%d", frame.ResponsePacketID, frame.ProcessingResult)
+		records = frame.SDR
+	default:
+		records = frame
+	}
+
+	if sds, ok := records.(*ServiceDataSet); ok {
+		for _, rec := range *sds {
+			describeRecord(&sb, &rec)
+		}
+	}
+	return sb.String()
+}
+
+// subrecordName название подзаписи. Код 20 используется и EGTS_SR_STATE_DATA, и EGTS_SR_ACCEL_DATA, поэтому
+// название определяется по разобранной подзаписи
+func subrecordName(srt byte, srd BinaryData) string {
+	if srt == SrType20 {
+		if _, ok := srd.(*SrStateData); ok {
+			return "EGTS_SR_STATE_DATA"
+		}
+		return "EGTS_SR_ACCEL_DATA"
+	}
+	return codeName(subrecordNames, srt)
+}
+
+// describeRecord дописывает описание записи и ее подзаписей
+func describeRecord(sb *strings.Builder, rec *ServiceDataRecord) {
+	fmt.Fprintf(sb, "\n  RN=%d %s", rec.RecordNumber, codeName(serviceNames, rec.SourceServiceType))
+	if rec.ObjectIDFieldExists == "1" {
+		fmt.Fprintf(sb, " OID=%d", rec.ObjectIdentifier)
+	}
+	if rec.TimeFieldExists == "1" {
+		fmt.Fprintf(sb, " TM=%s", NavTimeToTime(rec.Time).Format(time.RFC3339))
+	}
+
+	for _, rd := range rec.RecordDataSet {
+		srt, err := rd.subrecordType()
+		if err != nil {
+			srt = rd.SubrecordType
+		}
+		fmt.Fprintf(sb, "\n    %s", subrecordName(srt, rd.SubrecordData))
+
+		switch srd := rd.SubrecordData.(type) {
+		case *SrPosData:
+			lat, lon := signedCoordinates(srd)
+			fmt.Fprintf(sb, " NTM=%s LAT=%.6f LONG=%.6f SPD=%d DIR=%d VLD=%s",
+				srd.NavigationTime.Format(time.RFC3339), lat, lon, srd.Speed, srd.Direction, srd.VLD)
+		case *SrResponse:
+			fmt.Fprintf(sb, " CRN=%d RST=%d", srd.ConfirmedRecordNumber, srd.RecordStatus)
+		case *SrResultCode:
+			fmt.Fprintf(sb, " RCD=%d", srd.ResultCode)
+		case *SrTermIdentity:
+			fmt.Fprintf(sb, " TID=%d", srd.TerminalIdentifier)
+			if srd.IMEIE == "1" {
+				fmt.Fprintf(sb, " IMEI=%s", srd.IMEI)
+			}
+		case *SrStateData:
+			fmt.Fprintf(sb, " ST=%d MPSV=%.1f BBV=%.1f IBV=%.1f NMS=%s", srd.State, srd.MainPowerSourceVolts(),
+				srd.BackUpBatteryVolts(), srd.InternalBatteryVolts(), srd.NMS)
+		default:
+			if rd.SubrecordData != nil {
+				fmt.Fprintf(sb, " SRL=%d", rd.SubrecordData.Length())
+			}
+		}
+	}
+}
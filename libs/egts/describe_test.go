@@ -0,0 +1,56 @@
+package egts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPackage_String(t *testing.T) {
+	pkg := Package{}
+	if _, err := pkg.Decode(egtsPkgPosDataBytes); assert.NoError(t, err) {
+		assert.Equal(t, "EGTS_PT_APPDATA PID=138 FDL=35 HL=11 PRV=1 SKID=0 PRF=00 RTE=0 ENA=00 CMP=0 PR=11\n"+
+			"  RN=97 EGTS_TELEDATA_SERVICE OID=133552\n"+
+			"    EGTS_SR_POS_DATA NTM=2018-07-05T20:08:53Z LAT=55.553894 LONG=37.432367 SPD=200 DIR=172 VLD=1",
+			pkg.String())
+	}
+
+	resp := Package{}
+	if _, err := resp.Decode(goldenRoutedResponseBytes); assert.NoError(t, err) {
+		assert.Equal(t, "EGTS_PT_RESPONSE PID=2571 FDL=3 HL=16 PRV=1 SKID=0 PRF=00 RTE=1 ENA=00 CMP=0 PR=01 PRA=258 RCA=772 TTL=5\n"+
+			"  RPID=4660 PR=0", resp.String())
+	}
+
+	confirm := NewResponsePackage(3, 138, egtsPcOk, ServiceDataSet{
+		NewServiceDataRecord(4, 0, TeledataService, RecordDataSet{
+			{SubrecordData: &SrResponse{ConfirmedRecordNumber: 97, RecordStatus: egtsPcOk}},
+			{SubrecordType: 0xEE, SubrecordData: &RawSubrecord{SRT: 0xEE, Data: []byte{1, 2}}},
+		}),
+	})
+	assert.Equal(t, "EGTS_PT_RESPONSE PID=3 FDL=0 HL=11 PRV=1 SKID=0 PRF=00 RTE=0 ENA=00 CMP=0 PR="+DefaultPriority+"\n"+
+		"  RPID=138 PR=0\n  RN=4 EGTS_TELEDATA_SERVICE OID=0\n"+
+		"    EGTS_SR_RECORD_RESPONSE CRN=97 RST=0\n    unknown(238) SRL=2", confirm.String())
+}
+
+func TestPackage_HeaderString(t *testing.T) {
+	resp := Package{}
+	if _, err := resp.Decode(goldenRoutedResponseBytes); assert.NoError(t, err) {
+		assert.Equal(t, "EGTS_PT_RESPONSE PID=2571 FDL=3 HL=16 PRV=1 SKID=0 PRF=00 RTE=1 ENA=00 CMP=0 PR=01 PRA=258 RCA=772 TTL=5",
+			resp.HeaderString())
+	}
+}
+
+func TestPackage_StringType20(t *testing.T) {
+	pkg := NewAppdataPackage(1, ServiceDataSet{
+		NewServiceDataRecord(1, 0, TeledataService, RecordDataSet{
+			{SubrecordType: SrType20, SubrecordData: &SrStateData{State: 2, NMS: "1"}},
+			{SubrecordType: SrType20, SubrecordData: &RawSubrecord{SRT: SrType20, Data: []byte{1, 2, 3, 4, 5, 6, 7}}},
+		}),
+	})
+
+	lines := strings.Split(pkg.String(), "\n")
+	if assert.Len(t, lines, 4) {
+		assert.True(t, strings.HasPrefix(lines[2], "    EGTS_SR_STATE_DATA ST=2"), lines[2])
+		assert.Equal(t, "    EGTS_SR_ACCEL_DATA SRL=7", lines[3])
+	}
+}
@@ -0,0 +1,53 @@
+package egts
+
+import "fmt"
+
+//OdometerUnit единица измерения, в которой терминал передает пробег в поле ODM подзаписи EGTS_SR_POS_DATA
+type OdometerUnit uint8
+
+const (
+	//OdometerTenthKm пробег в десятых долях километра, как того требует стандарт
+	OdometerTenthKm OdometerUnit = iota
+	//OdometerMeters пробег в метрах (встречается у части терминалов)
+	OdometerMeters
+)
+
+// максимальное значение трехбайтового поля ODM
+const maxOdometerValue = 0xFFFFFF
+
+//DeviceProfile особенности конкретной модели терминала, которые нужно учитывать при разборе и формировании данных
+type DeviceProfile struct {
+	OdometerUnit OdometerUnit
+}
+
+//DefaultDeviceProfile профиль терминала, полностью соответствующего стандарту
+var DefaultDeviceProfile = DeviceProfile{OdometerUnit: OdometerTenthKm}
+
+// metersPerUnit количество метров в единице поля ODM
+func (d DeviceProfile) metersPerUnit() uint32 {
+	if d.OdometerUnit == OdometerMeters {
+		return 1
+	}
+	return 100
+}
+
+//OdometerMeters возвращает пробег в метрах с учетом единицы измерения из профиля терминала
+func (e *SrPosData) OdometerMeters(profile DeviceProfile) uint32 {
+	var raw uint32
+	for i := len(e.Odometer) - 1; i >= 0; i-- {
+		raw = raw<<8 | uint32(e.Odometer[i])
+	}
+	return raw * profile.metersPerUnit()
+}
+
+//SetOdometer записывает пробег в метрах в поле ODM в единицах, принятых в профиле терминала.
+//При необходимости значение округляется вниз до единицы поля
+func (e *SrPosData) SetOdometer(meters uint32, profile DeviceProfile) error {
+	raw := meters / profile.metersPerUnit()
+	if raw > maxOdometerValue {
+		return fmt.Errorf("Пробег %d м не помещается в поле ODM", meters)
+	}
+
+	e.Odometer = []byte{byte(raw), byte(raw >> 8), byte(raw >> 16)}
+	return nil
+}
@@ -0,0 +1,31 @@
+package egts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSrPosData_SetOdometerTenthKm(t *testing.T) {
+	pos := SrPosData{}
+
+	if assert.NoError(t, pos.SetOdometer(19100, DefaultDeviceProfile)) {
+		assert.Equal(t, []byte{0xbf, 0x00, 0x00}, pos.Odometer)
+		assert.Equal(t, uint32(19100), pos.OdometerMeters(DefaultDeviceProfile))
+	}
+
+	assert.Error(t, pos.SetOdometer(maxOdometerValue*100+100, DefaultDeviceProfile))
+}
+
+func TestSrPosData_SetOdometerMeters(t *testing.T) {
+	profile := DeviceProfile{OdometerUnit: OdometerMeters}
+	pos := SrPosData{}
+
+	if assert.NoError(t, pos.SetOdometer(19100, profile)) {
+		assert.Equal(t, []byte{0x9c, 0x4a, 0x00}, pos.Odometer)
+		assert.Equal(t, uint32(19100), pos.OdometerMeters(profile))
+	}
+
+	// одно и то же значение поля означает разный пробег в разных профилях
+	assert.Equal(t, uint32(1910000), pos.OdometerMeters(DefaultDeviceProfile))
+	assert.Error(t, pos.SetOdometer(maxOdometerValue+1, profile))
+}
@@ -2,14 +2,21 @@ package egts
 
 import (
 	"bytes"
+	"compress/flate"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"strconv"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
 )
 
 const DEFAULT_HEADER_LEN = 11
 
+// минимальное количество байт заголовка, необходимое для получения типа пакета (PRV..PT)
+const packetTypeOffset = 9
+
 // Package стуркура для описания пакета ЕГТС
 type Package struct {
 	ProtocolVersion           byte       `json:"PRV"`
@@ -30,201 +37,724 @@ type Package struct {
 	HeaderCheckSum            byte       `json:"HCS"`
 	ServicesFrameData         BinaryData `json:"SFRD"`
 	ServicesFrameDataCheckSum uint16     `json:"SFRCS"`
+
+	// Signature цифровая подпись (SIGD) пакета EGTS_PT_SIGNED_APPDATA, передается перед записями SFRD вместе
+	// с длиной SIGL. Алгоритм подписи определяется платформой, поэтому подпись хранится как есть. Для пакетов
+	// других типов не передается
+	Signature []byte `json:"SIGD,omitempty"`
+
+	// Strict строгий режим разбора: отклонения от спецификации приводят к ошибке, иначе
+	// корректируются с записью предупреждения в Warnings. Warnings очищается в начале каждого Decode
+	Strict   bool     `json:"-"`
+	Warnings []string `json:"-"`
+
+	// MaxSpeed максимально правдоподобная скорость в км/ч. Если задана, отметки EGTS_SR_POS_DATA с большей
+	// скоростью записываются в Warnings, а при ClampSpeed скорость в них ограничивается значением MaxSpeed
+	MaxSpeed   uint16 `json:"-"`
+	ClampSpeed bool   `json:"-"`
+
+	// AcceptBadCRC в нестрогом режиме принимать пакеты с неверной контрольной суммой заголовка или тела:
+	// разбор продолжается с записью предупреждения, результат проверки доступен через HeaderCRCValid и
+	// FrameDataCRCValid
+	AcceptBadCRC bool `json:"-"`
+
+	// AllowMissingSFRCS в нестрогом режиме принимать пакеты, в которых терминал не передал SFRCS после
+	// секции данных: тело разбирается без проверки суммы с записью предупреждения. По умолчанию отсутствие
+	// SFRCS считается ошибкой разбора
+	AllowMissingSFRCS bool `json:"-"`
+
+	// KeepUnknownSubrecords при разборе сохранять подзаписи неизвестных типов как RawSubrecord, а не
+	// возвращать ошибку. Типы подзаписей, которых нет в библиотеке, можно добавить через RegisterSubrecord
+	KeepUnknownSubrecords bool `json:"-"`
+
+	// Compressor алгоритм сжатия секции данных. Если задан или выставлен CMP = 1, при кодировании SFRD
+	// сжимается и выставляется CMP = 1, а при разборе пакета с CMP = 1 SFRD распаковывается. Без Compressor
+	// используется DeflateCompressor. SFRCS в обоих случаях считается по передаваемым, то есть сжатым, байтам
+	Compressor Compressor `json:"-"`
+
+	headerCRCMismatch    bool
+	frameDataCRCMismatch bool
+}
+
+//Compressor сжимает и распаковывает секцию данных пакета (SFRD)
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// maxFrameDataLength максимальная длина секции данных пакета (SFRD) по стандарту
+const maxFrameDataLength = 65517
+
+//DeflateCompressor сжатие секции данных алгоритмом deflate (RFC 1951), используется по умолчанию
+type DeflateCompressor struct{}
+
+//Compress сжимает данные
+func (DeflateCompressor) Compress(data []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w, err := flate.NewWriter(buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+//Decompress распаковывает данные. Распакованные данные длиннее максимальной длины SFRD (65517 байт)
+//считаются ошибкой
+func (DeflateCompressor) Decompress(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	// читается на байт больше допустимого, чтобы отличить превышение от данных максимальной длины
+	result, err := ioutil.ReadAll(io.LimitReader(r, maxFrameDataLength+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(result) > maxFrameDataLength {
+		return nil, fmt.Errorf("Распакованные данные превышают %d байт", maxFrameDataLength)
+	}
+	return result, nil
+}
+
+// compressor алгоритм сжатия секции данных пакета
+func (p *Package) compressor() Compressor {
+	if p.Compressor == nil {
+		return DeflateCompressor{}
+	}
+	return p.Compressor
+}
+
+//HeaderCRCValid признак совпадения контрольной суммы заголовка (HCS) при последнем разборе пакета
+func (p *Package) HeaderCRCValid() bool {
+	return !p.headerCRCMismatch
 }
 
-// Decode разбирает набор байт в структуру пакета
+//FrameDataCRCValid признак того, что при последнем разборе пакета не было найдено несовпадение контрольной суммы
+//тела (SFRCS). Если SFRCS не передана и пакет принят по AllowMissingSFRCS, сумма не проверялась, а в Warnings
+//записывается предупреждение
+func (p *Package) FrameDataCRCValid() bool {
+	return !p.frameDataCRCMismatch
+}
+
+// acceptBadCRC признак разбора с допуском неверных контрольных сумм
+func (p *Package) acceptBadCRC() bool {
+	return p.AcceptBadCRC && !p.Strict
+}
+
+// Decode разбирает набор байт в структуру пакета. Вместе с ошибкой возвращается код результата обработки, он же
+// доступен из ошибки через ResultCode
 func (p *Package) Decode(content []byte) (uint8, error) {
+	code, err := p.decode(content)
+	if pe, ok := err.(*ParseError); ok {
+		pe.Code = code
+	}
+	return code, err
+}
+
+func (p *Package) decode(content []byte) (uint8, error) {
 	var (
 		err   error
 		flags byte
 	)
+	p.headerCRCMismatch, p.frameDataCRCMismatch = false, false
+	// предупреждения относятся только к последнему разобранному пакету
+	p.Warnings = p.Warnings[:0]
+	// поля маршрутизации есть только в пакетах с RTE = 1 и не должны оставаться от предыдущего пакета
+	p.PeerAddress, p.RecipientAddress, p.TimeToLive = 0, 0, 0
 	buf := bytes.NewReader(content)
 	if p.ProtocolVersion, err = buf.ReadByte(); err != nil {
-		return egtsPcIncHeaderform, fmt.Errorf("Не удалось получить версию протокола: %v", err)
+		return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось получить версию протокола: %v", err)
 	}
 
 	if p.SecurityKeyID, err = buf.ReadByte(); err != nil {
-		return egtsPcIncHeaderform, fmt.Errorf("Не удалось получить идентификатор ключа: %v", err)
+		return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось получить идентификатор ключа: %v", err)
 	}
 
 	//разбираем флаги
 	if flags, err = buf.ReadByte(); err != nil {
-		return egtsPcIncHeaderform, fmt.Errorf("Не удалось флаги: %v", err)
+		return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось флаги: %v", err)
+	}
+	if err = p.ParseFlags(flags); err != nil {
+		return egtsPcUnsProtocol, parseErrorAt(2, err)
 	}
-	flagBits := fmt.Sprintf("%08b", flags)
-	p.Prefix = flagBits[:2]         // flags << 7, flags << 6
-	p.Route = flagBits[2:3]         // flags << 5
-	p.EncryptionAlg = flagBits[3:5] // flags << 4, flags << 3
-	p.Compression = flagBits[5:6]   // flags << 2
-	p.Priority = flagBits[6:]       // flags << 1, flags << 0
 
 	if p.HeaderLength, err = buf.ReadByte(); err != nil {
-		return egtsPcIncHeaderform, fmt.Errorf("Не удалось получить длину заголовка: %v", err)
+		return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось получить длину заголовка: %v", err)
 	}
 
 	if p.HeaderEncoding, err = buf.ReadByte(); err != nil {
-		return egtsPcIncHeaderform, fmt.Errorf("Не удалось получить метод кодирования: %v", err)
+		return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось получить метод кодирования: %v", err)
 	}
 
 	tmpIntBuf := make([]byte, 2)
-	if _, err = buf.Read(tmpIntBuf); err != nil {
-		return egtsPcIncHeaderform, fmt.Errorf("Не удалось получить длину секции данных: %v", err)
+	if _, err = io.ReadFull(buf, tmpIntBuf); err != nil {
+		return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось получить длину секции данных: %v", err)
 	}
 	p.FrameDataLength = binary.LittleEndian.Uint16(tmpIntBuf)
 
-	if _, err = buf.Read(tmpIntBuf); err != nil {
-		return egtsPcIncHeaderform, fmt.Errorf("Не удалось получить идентификатор пакета: %v", err)
+	if _, err = io.ReadFull(buf, tmpIntBuf); err != nil {
+		return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось получить идентификатор пакета: %v", err)
 	}
 	p.PacketIdentifier = binary.LittleEndian.Uint16(tmpIntBuf)
 
 	if p.PacketType, err = buf.ReadByte(); err != nil {
-		return egtsPcIncHeaderform, fmt.Errorf("Не удалось получить тип пакета: %v", err)
+		return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось получить тип пакета: %v", err)
 	}
 
 	if p.Route == "1" {
-		if _, err = buf.Read(tmpIntBuf); err != nil {
-			return egtsPcIncHeaderform, fmt.Errorf("Не удалось получить адрес апк отправителя: %v", err)
+		if _, err = io.ReadFull(buf, tmpIntBuf); err != nil {
+			return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось получить адрес апк отправителя: %v", err)
 		}
 		p.PeerAddress = binary.LittleEndian.Uint16(tmpIntBuf)
 
-		if _, err = buf.Read(tmpIntBuf); err != nil {
-			return egtsPcIncHeaderform, fmt.Errorf("Не удалось получить адрес апк получателя: %v", err)
+		if _, err = io.ReadFull(buf, tmpIntBuf); err != nil {
+			return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось получить адрес апк получателя: %v", err)
 		}
 		p.RecipientAddress = binary.LittleEndian.Uint16(tmpIntBuf)
 
 		if p.TimeToLive, err = buf.ReadByte(); err != nil {
-			return egtsPcIncHeaderform, fmt.Errorf("Не удалось получить TTL пакета: %v", err)
+			return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось получить TTL пакета: %v", err)
 		}
 	}
 
 	if p.HeaderCheckSum, err = buf.ReadByte(); err != nil {
-		return egtsPcIncHeaderform, fmt.Errorf("Не удалось получить crc заголовка: %v", err)
+		return egtsPcIncHeaderform, newShortBufferError(len(content)-buf.Len(), "Не удалось получить crc заголовка: %v", err)
+	}
+
+	hcs, err := headerCheckSum(content)
+	if err != nil {
+		return egtsPcIncHeaderform, &ParseError{Offset: int(p.HeaderLength) - 1, Msg: err.Error(), Err: ErrShortBuffer}
+	}
+	if p.HeaderCheckSum != hcs {
+		if !p.acceptBadCRC() {
+			return egtsPcHeaderCrcError, &ParseError{Offset: int(p.HeaderLength) - 1, Msg: "Не верная сумма заголовка пакета", Err: ErrHeaderCRC}
+		}
+		p.headerCRCMismatch = true
+		p.Warnings = append(p.Warnings, fmt.Sprintf("Не верная сумма заголовка пакета: %d, ожидалась %d", p.HeaderCheckSum, hcs))
 	}
 
-	if p.HeaderCheckSum != crc8(content[:p.HeaderLength-1]) {
-		return egtsPcHeaderCrcError, fmt.Errorf("Не верная сумма заголовка пакета")
+	// пакет может состоять только из заголовка, тогда секция данных и ее контрольная сумма не передаются
+	if p.FrameDataLength == 0 {
+		return egtsPcOk, nil
 	}
 
 	dataFrameBytes := make([]byte, p.FrameDataLength)
-	if _, err = buf.Read(dataFrameBytes); err != nil {
-		return egtsPcIncDataform, fmt.Errorf("Не считать тело пакета: %v", err)
+	if _, err = io.ReadFull(buf, dataFrameBytes); err != nil {
+		return egtsPcIncDataform, newShortBufferError(len(content)-buf.Len(), "Не считать тело пакета: %v", err)
+	}
+
+	// SFRCS проверяется по переданным байтам, поэтому для разбора расшифровывается и распаковывается копия
+	frame := dataFrameBytes
+	cipher, err := p.cipher()
+	if err != nil {
+		return egtsPcDecryptError, newParseError(2, "%v", err)
 	}
+	if cipher != nil {
+		if frame, err = cipher.Decrypt(p.SecurityKeyID, frame); err != nil {
+			return egtsPcDecryptError, newParseError(int(p.HeaderLength), "Не удалось расшифровать тело пакета: %v", err)
+		}
+	}
+	if p.Compression == "1" {
+		if frame, err = p.compressor().Decompress(frame); err != nil {
+			return egtsPcIncDataform, newParseError(int(p.HeaderLength), "Не удалось распаковать тело пакета: %v", err)
+		}
+	}
+
+	mode := decodeMode{strict: p.Strict, keepUnknown: p.KeepUnknownSubrecords, warnings: &p.Warnings}
 	switch p.PacketType {
 	case PtAppdataPacket:
-		p.ServicesFrameData = &ServiceDataSet{}
-		break
+		sds := &ServiceDataSet{}
+		p.ServicesFrameData = sds
+		err = sds.decode(frame, mode)
+	case PtSignedAppdataPacket:
+		var sdr []byte
+		if p.Signature, sdr, err = splitSignature(frame); err != nil {
+			return egtsPcIncDataform, parseErrorAt(int(p.HeaderLength), err)
+		}
+		sds := &ServiceDataSet{}
+		p.ServicesFrameData = sds
+		if err = sds.decode(sdr, mode); err != nil {
+			err = parseErrorAt(len(frame)-len(sdr), err)
+		}
 	case PtResponsePacket:
-		p.ServicesFrameData = &PtResponse{}
-		break
+		resp := &PtResponse{}
+		p.ServicesFrameData = resp
+		err = resp.decode(frame, mode)
 	default:
-		return egtsPcUnsType, fmt.Errorf("Неизвестный тип пакета: %d", p.PacketType)
+		return egtsPcUnsType, newParseError(packetTypeOffset, "Неизвестный тип пакета: %d", p.PacketType)
+	}
+
+	if err != nil {
+		return egtsPcIncDataform, parseErrorAt(int(p.HeaderLength), err)
+	}
+
+	if p.MaxSpeed > 0 {
+		p.checkSpeed()
 	}
 
-	if err = p.ServicesFrameData.Decode(dataFrameBytes); err != nil {
-		return egtsPcDecryptError, err
+	// некоторые терминалы не передают SFRCS, с AllowMissingSFRCS в нестрогом режиме тело принимается без
+	// проверки суммы
+	if buf.Len() == 0 && p.AllowMissingSFRCS && !p.Strict {
+		p.Warnings = append(p.Warnings, "Отсутствует контрольная сумма тела пакета (SFRCS), проверка не выполнялась")
+		return egtsPcOk, nil
 	}
 
 	crcBytes := make([]byte, 2)
-	if _, err = buf.Read(crcBytes); err != nil {
-		return egtsPcDecryptError, fmt.Errorf("Не удалось считать crc16 пакета: %v", err)
+	// без полной SFRCS целостность тела не подтверждена
+	if _, err = io.ReadFull(buf, crcBytes); err != nil {
+		return egtsPcDatacrcError, newShortBufferError(len(content)-buf.Len(), "Не удалось считать crc16 пакета: %v", err)
 	}
 	p.ServicesFrameDataCheckSum = binary.LittleEndian.Uint16(crcBytes)
 
-	if p.ServicesFrameDataCheckSum != crc16(content[p.HeaderLength:uint16(p.HeaderLength)+p.FrameDataLength]) {
-		return egtsPcHeaderCrcError, fmt.Errorf("Не верная сумма тела пакета")
+	if sfrcs := crc16(dataFrameBytes); p.ServicesFrameDataCheckSum != sfrcs {
+		if !p.acceptBadCRC() {
+			return egtsPcDatacrcError, &ParseError{Offset: int(p.HeaderLength) + int(p.FrameDataLength), Msg: "Не верная сумма тела пакета", Err: ErrDataCRC}
+		}
+		p.frameDataCRCMismatch = true
+		p.Warnings = append(p.Warnings, fmt.Sprintf("Не верная сумма тела пакета: %d, ожидалась %d", p.ServicesFrameDataCheckSum, sfrcs))
 	}
 	return egtsPcOk, err
 }
 
-// Encode кодирует струткуру в байтовую строку
+// checkSpeed отмечает и при необходимости ограничивает неправдоподобные скорости в отметках пакета
+func (p *Package) checkSpeed() {
+	sds, ok := p.ServicesFrameData.(*ServiceDataSet)
+	if !ok {
+		return
+	}
+
+	for _, rec := range *sds {
+		for _, subRec := range rec.RecordDataSet {
+			pos, ok := subRec.SubrecordData.(*SrPosData)
+			if !ok || pos.Speed <= p.MaxSpeed {
+				continue
+			}
+
+			p.Warnings = append(p.Warnings, fmt.Sprintf("Неправдоподобная скорость %d км/ч в записи %d (максимум %d)",
+				pos.Speed, rec.RecordNumber, p.MaxSpeed))
+			if p.ClampSpeed {
+				pos.Speed = p.MaxSpeed
+			}
+		}
+	}
+}
+
+// строковые представления битовых полей, чтобы разбор флагов не требовал выделения памяти
+var (
+	oneBit  = [2]string{"0", "1"}
+	twoBits = [4]string{"00", "01", "10", "11"}
+)
+
+// ParseFlags разбирает составной байт флагов заголовка. Биты префикса (PRF) для данной версии протокола
+// зарезервированы и должны быть равны 0: в строгом режиме пакет отклоняется, иначе биты сбрасываются
+// с записью предупреждения
+func (p *Package) ParseFlags(flags byte) error {
+	p.Prefix = twoBits[flags>>6&0x3]        // flags << 7, flags << 6
+	p.Route = oneBit[flags>>5&0x1]          // flags << 5
+	p.EncryptionAlg = twoBits[flags>>3&0x3] // flags << 4, flags << 3
+	p.Compression = oneBit[flags>>2&0x1]    // flags << 2
+	p.Priority = twoBits[flags&0x3]         // flags << 1, flags << 0
+
+	if p.Prefix != "00" {
+		if p.Strict {
+			return fmt.Errorf("Установлены зарезервированные биты префикса заголовка: %s", p.Prefix)
+		}
+		p.Warnings = append(p.Warnings, fmt.Sprintf("Сброшены зарезервированные биты префикса заголовка: %s", p.Prefix))
+		p.Prefix = "00"
+	}
+
+	return nil
+}
+
+// Encode кодирует струткуру в байтовую строку. Поля HL, FDL, HCS и SFRCS вычисляются при кодировании и
+// сохраняются в структуру, ранее заданные значения игнорируются
 func (p *Package) Encode() ([]byte, error) {
 	var (
-		result []byte
-		err    error
-		flags  uint64
+		sfrd  []byte
+		err   error
+		flags byte
 	)
-	buf := new(bytes.Buffer)
 
-	if err = buf.WriteByte(p.ProtocolVersion); err != nil {
-		return result, fmt.Errorf("Не удалось записать версию протокола: %v", err)
+	if p.ServicesFrameData != nil {
+		sfrd, err = p.ServicesFrameData.Encode()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.PacketType == PtSignedAppdataPacket {
+		if sfrd, err = joinSignature(p.Signature, sfrd); err != nil {
+			return nil, err
+		}
 	}
-	if err = buf.WriteByte(p.SecurityKeyID); err != nil {
-		return result, fmt.Errorf("Не удалось записать  идентификатор ключа: %v", err)
+	if (p.Compressor != nil || p.Compression == "1") && len(sfrd) > 0 {
+		if sfrd, err = p.compressor().Compress(sfrd); err != nil {
+			return nil, fmt.Errorf("Не удалось сжать секцию данных: %v", err)
+		}
+		p.Compression = "1"
+	}
+	if len(sfrd) > 0 {
+		cipher, err := p.cipher()
+		if err != nil {
+			return nil, err
+		}
+		if cipher != nil {
+			if sfrd, err = cipher.Encrypt(p.SecurityKeyID, sfrd); err != nil {
+				return nil, fmt.Errorf("Не удалось зашифровать секцию данных: %v", err)
+			}
+		}
 	}
 
 	//собираем флаги
-	flagsBits := p.Prefix + p.Route + p.EncryptionAlg + p.Compression + p.Priority
-	if flags, err = strconv.ParseUint(flagsBits, 2, 8); err != nil {
-		return result, fmt.Errorf("Не удалось сгенерировать байт флагов: %v", err)
+	if flags, err = p.flagsByte(); err != nil {
+		return nil, err
 	}
 
-	if err = buf.WriteByte(uint8(flags)); err != nil {
-		return result, fmt.Errorf("Не удалось записать флаги: %v", err)
+	if len(sfrd) > math.MaxUint16 {
+		return nil, fmt.Errorf("Длина секции данных %d байт превышает максимально допустимую", len(sfrd))
 	}
+	p.FrameDataLength = uint16(len(sfrd))
 
-	if p.HeaderLength == 0 {
-		p.HeaderLength = DEFAULT_HEADER_LEN
-		if p.Route == "1" {
-			p.HeaderLength += 5
-		}
+	// PRA, RCA и TTL передаются только при RTE=1, поэтому HL всегда вычисляется по флагу маршрутизации,
+	// а не берется из ранее разобранного заголовка
+	p.HeaderLength = DEFAULT_HEADER_LEN
+	if p.Route == "1" {
+		p.HeaderLength += 5
 	}
 
-	if err = buf.WriteByte(p.HeaderLength); err != nil {
-		return result, fmt.Errorf("Не удалось записать длину заголовка: %v", err)
+	// пакет собирается в буфер итогового размера: заголовок, SFRD и SFRCS, если есть SFRD
+	size := int(p.HeaderLength) + len(sfrd)
+	if len(sfrd) > 0 {
+		size += 2
 	}
+	result := make([]byte, size)
+
+	result[0] = p.ProtocolVersion
+	result[1] = p.SecurityKeyID
+	result[2] = flags
+	result[3] = p.HeaderLength
+	result[4] = p.HeaderEncoding
+	binary.LittleEndian.PutUint16(result[5:], p.FrameDataLength)
+	binary.LittleEndian.PutUint16(result[7:], p.PacketIdentifier)
+	result[packetTypeOffset] = p.PacketType
 
-	if err = buf.WriteByte(p.HeaderEncoding); err != nil {
-		return result, fmt.Errorf("Не удалось записать метод кодирования: %v", err)
+	if p.Route == "1" {
+		binary.LittleEndian.PutUint16(result[10:], p.PeerAddress)
+		binary.LittleEndian.PutUint16(result[12:], p.RecipientAddress)
+		result[14] = p.TimeToLive
 	}
 
-	var sfrd []byte
-	if p.ServicesFrameData != nil {
-		sfrd, err = p.ServicesFrameData.Encode()
-		if err != nil {
-			return result, err
-		}
+	hcsOffset := int(p.HeaderLength) - 1
+	p.HeaderCheckSum = crc8(result[:hcsOffset])
+	result[hcsOffset] = p.HeaderCheckSum
+
+	// SFRCS передается, только если есть SFRD
+	p.ServicesFrameDataCheckSum = 0
+	if len(sfrd) > 0 {
+		copy(result[p.HeaderLength:], sfrd)
+		p.ServicesFrameDataCheckSum = crc16(sfrd)
+		binary.LittleEndian.PutUint16(result[size-2:], p.ServicesFrameDataCheckSum)
 	}
-	p.FrameDataLength = uint16(len(sfrd))
-	if err = binary.Write(buf, binary.LittleEndian, p.FrameDataLength); err != nil {
-		return result, fmt.Errorf("Не удалось записать длину секции данных: %v", err)
+
+	return result, nil
+}
+
+// maxSignatureLen максимальная длина подписи SIGD по спецификации
+const maxSignatureLen = 512
+
+// joinSignature добавляет перед записями SFRD длину подписи SIGL и саму подпись SIGD
+func joinSignature(sig, sdr []byte) ([]byte, error) {
+	if len(sig) > maxSignatureLen {
+		return nil, fmt.Errorf("Длина подписи %d байт превышает максимально допустимую: %d", len(sig), maxSignatureLen)
 	}
 
-	if err = binary.Write(buf, binary.LittleEndian, p.PacketIdentifier); err != nil {
-		return result, fmt.Errorf("Не удалось записать идентификатор пакета: %v", err)
+	result := make([]byte, 2, 2+len(sig)+len(sdr))
+	binary.LittleEndian.PutUint16(result, uint16(len(sig)))
+	result = append(result, sig...)
+	return append(result, sdr...), nil
+}
+
+// splitSignature отделяет подпись SIGD от записей SFRD пакета EGTS_PT_SIGNED_APPDATA
+func splitSignature(frame []byte) ([]byte, []byte, error) {
+	if len(frame) < 2 {
+		return nil, nil, newShortBufferError(len(frame), "Не удалось получить длину подписи: %d байт", len(frame))
 	}
 
-	if err = buf.WriteByte(p.PacketType); err != nil {
-		return result, fmt.Errorf("Не удалось записать идентификатор пакета: %v", err)
+	sigLen := int(binary.LittleEndian.Uint16(frame))
+	if sigLen > maxSignatureLen {
+		return nil, nil, newParseError(0, "Длина подписи %d байт превышает максимально допустимую: %d", sigLen, maxSignatureLen)
+	}
+	if len(frame) < 2+sigLen {
+		return nil, nil, newShortBufferError(len(frame), "Длина подписи %d превышает оставшиеся данные: %d", sigLen, len(frame)-2)
 	}
 
-	if p.Route == "1" {
-		if err = binary.Write(buf, binary.LittleEndian, p.PeerAddress); err != nil {
-			return result, fmt.Errorf("Не удалось записать адрес апк отправителя: %v", err)
-		}
+	var sig []byte
+	if sigLen > 0 {
+		sig = append([]byte{}, frame[2:2+sigLen]...)
+	}
+	return sig, frame[2+sigLen:], nil
+}
 
-		if err = binary.Write(buf, binary.LittleEndian, p.RecipientAddress); err != nil {
-			return result, fmt.Errorf("Не удалось записать адрес апк получателя: %v", err)
-		}
+// checkFlagBits проверяет, что битовое поле name записано ровно width двоичными разрядами, иначе байт флагов
+// был бы собран со сдвигом остальных полей
+func checkFlagBits(name, value string, width int) error {
+	if len(value) != width || strings.Trim(value, "01") != "" {
+		return fmt.Errorf("Некорректное значение поля %s: %q, ожидается %d бит", name, value, width)
+	}
+	return nil
+}
 
-		if err = buf.WriteByte(p.TimeToLive); err != nil {
-			return result, fmt.Errorf("Не удалось записать TTL пакета: %v", err)
-		}
+// flagsByte собирает составной байт флагов заголовка
+func (p *Package) flagsByte() (byte, error) {
+	priority := p.Priority
+	if priority == "" {
+		priority = DefaultPriority
 	}
 
-	buf.WriteByte(crc8(buf.Bytes()))
+	var flags byte
+	for _, field := range [...]struct {
+		name  string
+		value string
+		width int
+	}{
+		{"PRF", p.Prefix, 2},
+		{"RTE", p.Route, 1},
+		{"ENA", p.EncryptionAlg, 2},
+		{"CMP", p.Compression, 1},
+		{"PR", priority, 2},
+	} {
+		if len(field.value) != field.width {
+			return 0, checkFlagBits(field.name, field.value, field.width)
+		}
 
-	if p.FrameDataLength > 0 {
-		buf.Write(sfrd)
-		if err := binary.Write(buf, binary.LittleEndian, crc16(sfrd)); err != nil {
-			return result, fmt.Errorf("Не удалось записать crc16 пакета: %v", err)
+		for i := 0; i < len(field.value); i++ {
+			switch field.value[i] {
+			case '0':
+				flags <<= 1
+			case '1':
+				flags = flags<<1 | 1
+			default:
+				return 0, checkFlagBits(field.name, field.value, field.width)
+			}
 		}
 	}
-
-	result = buf.Bytes()
-	return result, err
+	return flags, nil
 }
 
 //ToBytes переводит пакет в json
 func (p *Package) ToBytes() ([]byte, error) {
 	return json.Marshal(p)
 }
+
+//PeekPacketType возвращает тип пакета (PT), не разбирая пакет целиком. Проверяется только версия протокола
+func PeekPacketType(content []byte) (byte, error) {
+	if len(content) <= packetTypeOffset {
+		return 0, fmt.Errorf("Недостаточно данных для получения типа пакета: %d байт", len(content))
+	}
+
+	if content[0] != 0x01 {
+		return 0, fmt.Errorf("Неподдерживаемая версия протокола: %d", content[0])
+	}
+
+	return content[packetTypeOffset], nil
+}
+
+//Validate проверяет заголовок пакета на соответствие протоколу: PRV = 1, PRF = 00, допустимый тип пакета и
+//согласованность полей маршрутизации с RTE. Возвращает первое нарушение в виде *ParseError со смещением поля
+//и кодом EGTS_PC_INC_HEADERFORM
+func (p *Package) Validate() error {
+	invalid := func(offset int, format string, a ...interface{}) error {
+		return &ParseError{Offset: offset, Msg: fmt.Sprintf(format, a...), Code: egtsPcIncHeaderform}
+	}
+
+	if p.ProtocolVersion != 0x01 {
+		return invalid(0, "Неподдерживаемая версия протокола: %d", p.ProtocolVersion)
+	}
+	if p.Prefix != "00" {
+		return invalid(2, "Недопустимый префикс заголовка: %q", p.Prefix)
+	}
+
+	switch p.PacketType {
+	case PtResponsePacket, PtAppdataPacket, PtSignedAppdataPacket:
+	default:
+		return invalid(packetTypeOffset, "Неизвестный тип пакета: %d", p.PacketType)
+	}
+
+	switch p.Route {
+	case "1":
+		if p.HeaderLength != 0 && p.HeaderLength != DEFAULT_HEADER_LEN+5 {
+			return invalid(3, "Длина заголовка %d не соответствует пакету с маршрутизацией", p.HeaderLength)
+		}
+		// пакет с исчерпанным TTL не должен передаваться дальше
+		if p.TimeToLive == 0 {
+			return invalid(14, "Нулевое время жизни пакета с маршрутизацией")
+		}
+	case "0":
+		if p.HeaderLength != 0 && p.HeaderLength != DEFAULT_HEADER_LEN {
+			return invalid(3, "Длина заголовка %d не соответствует пакету без маршрутизации", p.HeaderLength)
+		}
+		if p.PeerAddress != 0 || p.RecipientAddress != 0 || p.TimeToLive != 0 {
+			return invalid(2, "Заданы поля маршрутизации PRA, RCA, TTL при RTE = 0")
+		}
+	default:
+		return invalid(2, "Некорректное значение поля RTE: %q", p.Route)
+	}
+
+	return nil
+}
+
+//SetRouting включает маршрутизацию пакета (RTE = 1) с адресами отправителя pra, получателя rca и временем
+//жизни ttl. HL, HCS и SFRCS пересчитываются при следующем Encode
+func (p *Package) SetRouting(pra, rca uint16, ttl byte) {
+	p.Route = "1"
+	p.PeerAddress = pra
+	p.RecipientAddress = rca
+	p.TimeToLive = ttl
+	p.HeaderLength = DEFAULT_HEADER_LEN + 5
+}
+
+//DecrementTTL уменьшает время жизни пакета при передаче через очередной узел. Возвращает false, если время
+//жизни исчерпано и пакет пересылать дальше нельзя
+func (p *Package) DecrementTTL() bool {
+	if p.TimeToLive == 0 {
+		return false
+	}
+	p.TimeToLive--
+	return p.TimeToLive > 0
+}
+
+//ChecksumReport контрольные суммы пакета: переданные устройством и вычисленные по содержимому пакета
+type ChecksumReport struct {
+	HeaderCheckSum                    byte
+	ComputedHeaderCheckSum            byte
+	ServicesFrameDataCheckSum         uint16
+	ComputedServicesFrameDataCheckSum uint16
+}
+
+//Valid признак совпадения переданных и вычисленных контрольных сумм
+func (r ChecksumReport) Valid() bool {
+	return r.HeaderCheckSum == r.ComputedHeaderCheckSum &&
+		r.ServicesFrameDataCheckSum == r.ComputedServicesFrameDataCheckSum
+}
+
+//InspectChecksums возвращает значения HCS и SFRCS пакета в том виде, в каком их передало устройство, вместе с
+//вычисленными. В отличие от Decode, несовпадение сумм ошибкой не считается. Для пакета без тела SFRCS не заполняется
+func InspectChecksums(content []byte) (ChecksumReport, error) {
+	report := ChecksumReport{}
+	if len(content) < DEFAULT_HEADER_LEN {
+		return report, fmt.Errorf("Недостаточно данных для получения заголовка: %d байт", len(content))
+	}
+
+	headerLen := int(content[3])
+	if headerLen < DEFAULT_HEADER_LEN || len(content) < headerLen {
+		return report, fmt.Errorf("Некорректная длина заголовка пакета: %d", headerLen)
+	}
+	report.HeaderCheckSum = content[headerLen-1]
+	report.ComputedHeaderCheckSum = crc8(content[:headerLen-1])
+
+	bodyLen := int(binary.LittleEndian.Uint16(content[5:7]))
+	if bodyLen == 0 {
+		return report, nil
+	}
+
+	if len(content) < headerLen+bodyLen+2 {
+		return report, fmt.Errorf("Недостаточно данных для получения crc16 пакета: %d байт", len(content))
+	}
+	report.ServicesFrameDataCheckSum = binary.LittleEndian.Uint16(content[headerLen+bodyLen:])
+	report.ComputedServicesFrameDataCheckSum = crc16(content[headerLen : headerLen+bodyLen])
+
+	return report, nil
+}
+
+//LooksLikeEGTS быстрая проверка, похожи ли данные на начало пакета ЕГТС: PRV = 1, PRF = 00, допустимая длина
+//заголовка и совпадающая контрольная сумма заголовка. Тело пакета не разбирается
+func LooksLikeEGTS(data []byte) bool {
+	if len(data) < DEFAULT_HEADER_LEN || data[0] != 0x01 || data[2]>>6 != 0 {
+		return false
+	}
+
+	headerLen := int(data[3])
+	if headerLen != DEFAULT_HEADER_LEN && headerLen != DEFAULT_HEADER_LEN+5 {
+		return false
+	}
+
+	return len(data) >= headerLen && data[headerLen-1] == crc8(data[:headerLen-1])
+}
+
+//CountRecords подсчитывает записи уровня поддержки услуг и подзаписи в пакете, разбирая только их длины
+//и флаги, без создания структур подзаписей. Контрольные суммы не проверяются
+func CountRecords(data []byte) (records, subrecords int, err error) {
+	if len(data) < DEFAULT_HEADER_LEN {
+		return 0, 0, newShortBufferError(len(data), "Недостаточно данных для получения заголовка: %d байт", len(data))
+	}
+
+	headerLen := int(data[3])
+	if headerLen < DEFAULT_HEADER_LEN || len(data) < headerLen {
+		return 0, 0, newParseError(3, "Некорректная длина заголовка пакета: %d", headerLen)
+	}
+
+	bodyLen := int(binary.LittleEndian.Uint16(data[5:7]))
+	if len(data) < headerLen+bodyLen {
+		return 0, 0, newShortBufferError(len(data), "Недостаточно данных для получения тела пакета: %d байт", len(data))
+	}
+	sfrd := data[headerLen : headerLen+bodyLen]
+	offset := headerLen
+
+	switch data[packetTypeOffset] {
+	case PtAppdataPacket:
+	case PtSignedAppdataPacket:
+		sig, sdr, err := splitSignature(sfrd)
+		if err != nil {
+			return 0, 0, parseErrorAt(offset, err)
+		}
+		offset += 2 + len(sig)
+		sfrd = sdr
+	case PtResponsePacket:
+		// записи в ответе следуют за RPID и PR
+		if len(sfrd) > 0 {
+			if len(sfrd) < 3 {
+				return 0, 0, newShortBufferError(offset+len(sfrd), "Недостаточно данных для получения результата обработки: %d байт", len(sfrd))
+			}
+			sfrd = sfrd[3:]
+			offset += 3
+		}
+	default:
+		return 0, 0, newParseError(packetTypeOffset, "Неизвестный тип пакета: %d", data[packetTypeOffset])
+	}
+
+	countSubrecord := func(uint8, []byte) error {
+		subrecords++
+		return nil
+	}
+	for pos := 0; pos < len(sfrd); {
+		// RL, RN, RFL
+		if len(sfrd)-pos < 5 {
+			return records, subrecords, newShortBufferError(offset+len(sfrd), "Недостаточно данных для получения заголовка записи SDR")
+		}
+		recordLen := int(binary.LittleEndian.Uint16(sfrd[pos:]))
+
+		// RL, RN, RFL, SST, RST и необязательные OID, EVID, TM
+		recordHeaderLen := 7
+		for _, flag := range []byte{0x01, 0x02, 0x04} {
+			if sfrd[pos+4]&flag != 0 {
+				recordHeaderLen += 4
+			}
+		}
+		if len(sfrd)-pos < recordHeaderLen+recordLen {
+			return records, subrecords, newShortBufferError(offset+len(sfrd), "Длина записи SDR %d превышает оставшиеся данные пакета", recordLen)
+		}
+
+		rdOffset := pos + recordHeaderLen
+		if err = WalkTLV(sfrd[rdOffset:rdOffset+recordLen], countSubrecord); err != nil {
+			return records, subrecords, parseErrorAt(offset+rdOffset, err)
+		}
+		records++
+		pos = rdOffset + recordLen
+	}
+
+	return records, subrecords, nil
+}
@@ -1,7 +1,12 @@
 package egts
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"testing"
 	"time"
 )
@@ -36,6 +41,7 @@ func TestEgtsPackagePosData_Encode(t *testing.T) {
 				TimeFieldExists:          "0",
 				EventIDFieldExists:       "0",
 				ObjectIDFieldExists:      "1",
+				RawFlags:                 0x99,
 				ObjectIdentifier:         133552,
 				SourceServiceType:        2,
 				RecipientServiceType:     2,
@@ -56,6 +62,7 @@ func TestEgtsPackagePosData_Encode(t *testing.T) {
 							DirectionHighestBit: 1,
 							AltitudeSign:        0,
 							Speed:               200,
+							rawSpeed:            2000,
 							Direction:           172,
 							Odometer:            []byte{0x01, 0x00, 0x00},
 							DigitalInputs:       0,
@@ -100,6 +107,7 @@ func TestEgtsPackagePosData_Decode(t *testing.T) {
 				TimeFieldExists:          "0",
 				EventIDFieldExists:       "0",
 				ObjectIDFieldExists:      "1",
+				RawFlags:                 0x99,
 				ObjectIdentifier:         133552,
 				SourceServiceType:        2,
 				RecipientServiceType:     2,
@@ -122,6 +130,7 @@ func TestEgtsPackagePosData_Decode(t *testing.T) {
 							DirectionHighestBit: 1,
 							AltitudeSign:        0,
 							Speed:               200,
+							rawSpeed:            2000,
 							Direction:           172,
 							Odometer:            []byte{0x01, 0x00, 0x00},
 							DigitalInputs:       0,
@@ -166,6 +175,7 @@ func TestFullCycleCoding(t *testing.T) {
 				TimeFieldExists:          "0",
 				EventIDFieldExists:       "0",
 				ObjectIDFieldExists:      "1",
+				RawFlags:                 0x99,
 				ObjectIdentifier:         133552,
 				SourceServiceType:        2,
 				RecipientServiceType:     2,
@@ -188,6 +198,7 @@ func TestFullCycleCoding(t *testing.T) {
 							DirectionHighestBit: 1,
 							AltitudeSign:        0,
 							Speed:               200,
+							rawSpeed:            2000,
 							Direction:           172,
 							Odometer:            []byte{0x01, 0x00, 0x00},
 							DigitalInputs:       0,
@@ -237,6 +248,7 @@ func TestRebuildCycleCoding(t *testing.T) {
 				TimeFieldExists:          "0",
 				EventIDFieldExists:       "1",
 				ObjectIDFieldExists:      "0",
+				RawFlags:                 0x12,
 				EventIdentifier:          3436,
 				SourceServiceType:        2,
 				RecipientServiceType:     2,
@@ -259,6 +271,7 @@ func TestRebuildCycleCoding(t *testing.T) {
 							DirectionHighestBit: 0,
 							AltitudeSign:        0,
 							Speed:               34,
+							rawSpeed:            340,
 							Direction:           172,
 							Odometer:            []byte{0xbf, 0x00, 0x00},
 							DigitalInputs:       144,
@@ -323,6 +336,7 @@ func TestRebuildOID(t *testing.T) {
 				TimeFieldExists:          "0",
 				EventIDFieldExists:       "1",
 				ObjectIDFieldExists:      "1",
+				RawFlags:                 0x13,
 				EventIdentifier:          3436,
 				ObjectIdentifier:         326009033,
 				SourceServiceType:        2,
@@ -346,6 +360,7 @@ func TestRebuildOID(t *testing.T) {
 							DirectionHighestBit: 0,
 							AltitudeSign:        0,
 							Speed:               34,
+							rawSpeed:            340,
 							Direction:           172,
 							Odometer:            []byte{0xbf, 0x00, 0x00},
 							DigitalInputs:       144,
@@ -378,3 +393,781 @@ func TestRebuildOID(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func TestPeekPacketType(t *testing.T) {
+	for _, pt := range []byte{PtResponsePacket, PtAppdataPacket, PtSignedAppdataPacket} {
+		pkg := make([]byte, len(egtsPkgPosDataBytes))
+		copy(pkg, egtsPkgPosDataBytes)
+		pkg[9] = pt
+
+		packetType, err := PeekPacketType(pkg)
+		if assert.NoError(t, err) {
+			assert.Equal(t, pt, packetType)
+		}
+	}
+
+	_, err := PeekPacketType(egtsPkgPosDataBytes[:9])
+	assert.Error(t, err)
+
+	_, err = PeekPacketType(append([]byte{0x02}, egtsPkgPosDataBytes[1:]...))
+	assert.Error(t, err)
+}
+
+// эталонный пакет фиксирует формат передачи данных (порядок байт little-endian, расположение полей заголовка
+// с маршрутизацией), при любом изменении раскладки тест должен упасть
+var goldenRoutedResponseBytes = []byte{
+	0x01,       // PRV
+	0x00,       // SKID
+	0x21,       // PRF=00 RTE=1 ENA=00 CMP=0 PR=01
+	0x10,       // HL = 16
+	0x00,       // HE
+	0x03, 0x00, // FDL = 3
+	0x0B, 0x0A, // PID = 0x0A0B
+	0x00,       // PT = EGTS_PT_RESPONSE
+	0x02, 0x01, // PRA = 0x0102
+	0x04, 0x03, // RCA = 0x0304
+	0x05,       // TTL
+	0xC0,       // HCS
+	0x34, 0x12, // RPID = 0x1234
+	0x00,       // PR
+	0xE8, 0xB0, // SFRCS
+}
+
+func TestWireFormatGolden(t *testing.T) {
+	pkg := Package{
+		ProtocolVersion:  1,
+		SecurityKeyID:    0,
+		Prefix:           "00",
+		Route:            "1",
+		EncryptionAlg:    "00",
+		Compression:      "0",
+		Priority:         "01",
+		HeaderEncoding:   0,
+		PacketIdentifier: 0x0A0B,
+		PacketType:       PtResponsePacket,
+		PeerAddress:      0x0102,
+		RecipientAddress: 0x0304,
+		TimeToLive:       5,
+		ServicesFrameData: &PtResponse{
+			ResponsePacketID: 0x1234,
+			ProcessingResult: 0,
+		},
+	}
+
+	pkgBytes, err := pkg.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, goldenRoutedResponseBytes, pkgBytes, "формат пакета изменился")
+	}
+
+	decodedPkg := Package{}
+	if _, err = decodedPkg.Decode(goldenRoutedResponseBytes); assert.NoError(t, err) {
+		assert.Equal(t, uint16(0x0A0B), decodedPkg.PacketIdentifier)
+		assert.Equal(t, uint16(0x0102), decodedPkg.PeerAddress)
+		assert.Equal(t, uint16(0x0304), decodedPkg.RecipientAddress)
+		assert.Equal(t, uint16(0x1234), decodedPkg.ServicesFrameData.(*PtResponse).ResponsePacketID)
+	}
+}
+
+func TestPackage_ParseFlags(t *testing.T) {
+	// PRF=11 RTE=0 ENA=00 CMP=0 PR=11
+	flags := byte(0xC3)
+
+	lenientPkg := Package{}
+	if assert.NoError(t, lenientPkg.ParseFlags(flags)) {
+		assert.Equal(t, "00", lenientPkg.Prefix)
+		assert.Equal(t, "0", lenientPkg.Route)
+		assert.Equal(t, "11", lenientPkg.Priority)
+		assert.Len(t, lenientPkg.Warnings, 1)
+	}
+
+	strictPkg := Package{Strict: true}
+	assert.Error(t, strictPkg.ParseFlags(flags))
+
+	pkg := make([]byte, len(egtsPkgPosDataBytes))
+	copy(pkg, egtsPkgPosDataBytes)
+	pkg[2] |= 0xC0
+	pkg[10] = crc8(pkg[:10])
+
+	lenientPkg = Package{}
+	_, err := lenientPkg.Decode(pkg)
+	assert.NoError(t, err)
+
+	strictPkg = Package{Strict: true}
+	resultCode, err := strictPkg.Decode(pkg)
+	if assert.Error(t, err) {
+		assert.Equal(t, egtsPcUnsProtocol, resultCode)
+	}
+}
+
+func TestPackage_ParseFlagsAllValues(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		pkg := Package{}
+		if !assert.NoError(t, pkg.ParseFlags(byte(i))) {
+			return
+		}
+
+		flags, err := pkg.flagsByte()
+		if assert.NoError(t, err) {
+			// зарезервированные биты префикса сбрасываются в нестрогом режиме
+			assert.Equal(t, byte(i)&0x3F, flags)
+		}
+	}
+
+	pkg := Package{}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = pkg.ParseFlags(0x23)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func BenchmarkPackage_ParseFlags(b *testing.B) {
+	pkg := Package{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = pkg.ParseFlags(byte(i) & 0x3F)
+	}
+}
+
+func TestPackage_HeaderOnly(t *testing.T) {
+	pkg := Package{
+		ProtocolVersion:  1,
+		Prefix:           "00",
+		Route:            "0",
+		EncryptionAlg:    "00",
+		Compression:      "0",
+		Priority:         "00",
+		PacketIdentifier: 5,
+		PacketType:       PtAppdataPacket,
+	}
+
+	headerBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, headerBytes, DEFAULT_HEADER_LEN)
+	assert.Equal(t, crc8(headerBytes[:DEFAULT_HEADER_LEN-1]), headerBytes[DEFAULT_HEADER_LEN-1])
+
+	decodedPkg := Package{}
+	resultCode, err := decodedPkg.Decode(headerBytes)
+	if assert.NoError(t, err) {
+		assert.Equal(t, egtsPcOk, resultCode)
+		assert.Equal(t, uint16(0), decodedPkg.FrameDataLength)
+		assert.Equal(t, uint16(5), decodedPkg.PacketIdentifier)
+		assert.Nil(t, decodedPkg.ServicesFrameData)
+	}
+}
+
+func TestInspectChecksums(t *testing.T) {
+	report, err := InspectChecksums(goldenRoutedResponseBytes)
+	if assert.NoError(t, err) {
+		assert.True(t, report.Valid())
+		assert.Equal(t, byte(0xC0), report.HeaderCheckSum)
+		assert.Equal(t, uint16(0xB0E8), report.ServicesFrameDataCheckSum)
+	}
+
+	// устройство передало неверные суммы: их значения видны рядом с вычисленными
+	pkg := append([]byte{}, goldenRoutedResponseBytes...)
+	pkg[15] = 0x00
+	pkg[len(pkg)-1] = 0x00
+	report, err = InspectChecksums(pkg)
+	if assert.NoError(t, err) {
+		assert.False(t, report.Valid())
+		assert.Equal(t, ChecksumReport{
+			HeaderCheckSum:                    0x00,
+			ComputedHeaderCheckSum:            0xC0,
+			ServicesFrameDataCheckSum:         0x00E8,
+			ComputedServicesFrameDataCheckSum: 0xB0E8,
+		}, report)
+	}
+
+	// разбор пакета не подменяет переданную сумму вычисленной
+	decodedPkg := Package{}
+	_, err = decodedPkg.Decode(pkg)
+	assert.Error(t, err)
+	assert.Equal(t, byte(0x00), decodedPkg.HeaderCheckSum)
+
+	_, err = InspectChecksums(goldenRoutedResponseBytes[:len(goldenRoutedResponseBytes)-1])
+	assert.Error(t, err)
+}
+
+func TestLooksLikeEGTS(t *testing.T) {
+	assert.True(t, LooksLikeEGTS(egtsPkgPosDataBytes))
+	assert.True(t, LooksLikeEGTS(goldenRoutedResponseBytes))
+
+	assert.False(t, LooksLikeEGTS([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n")))
+	assert.False(t, LooksLikeEGTS([]byte{0x01, 0x00, 0x00, 0x0B, 0x00, 0x23, 0x00, 0x8A, 0x00, 0x01, 0x00}))
+	assert.False(t, LooksLikeEGTS(egtsPkgPosDataBytes[:5]))
+
+	// PRF != 00
+	pkg := append([]byte{}, egtsPkgPosDataBytes...)
+	pkg[2] |= 0x40
+	assert.False(t, LooksLikeEGTS(pkg))
+}
+
+func TestPackage_MaxSpeed(t *testing.T) {
+	pkgBytes, err := NewTelematicsPackage(1, 1001, testEgtsSrPosData.NavigationTime, 55.5, 37.25, 500).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	flagged := Package{MaxSpeed: 150}
+	if _, err = flagged.Decode(pkgBytes); assert.NoError(t, err) {
+		rec := (*flagged.ServicesFrameData.(*ServiceDataSet))[0]
+		assert.Equal(t, uint16(500), rec.RecordDataSet[0].SubrecordData.(*SrPosData).Speed)
+		assert.Len(t, flagged.Warnings, 1)
+	}
+
+	clamped := Package{MaxSpeed: 150, ClampSpeed: true}
+	if _, err = clamped.Decode(pkgBytes); assert.NoError(t, err) {
+		rec := (*clamped.ServicesFrameData.(*ServiceDataSet))[0]
+		assert.Equal(t, uint16(150), rec.RecordDataSet[0].SubrecordData.(*SrPosData).Speed)
+		assert.Len(t, clamped.Warnings, 1)
+	}
+
+	unchecked := Package{}
+	if _, err = unchecked.Decode(pkgBytes); assert.NoError(t, err) {
+		assert.Empty(t, unchecked.Warnings)
+	}
+}
+
+func TestPackage_MissingSFRCS(t *testing.T) {
+	noCrc := egtsPkgPosDataBytes[:len(egtsPkgPosDataBytes)-2]
+
+	// по умолчанию отсутствие SFRCS считается ошибкой
+	_, err := (&Package{}).Decode(noCrc)
+	if assert.Error(t, err) {
+		pe, ok := err.(*ParseError)
+		if assert.True(t, ok) {
+			assert.Equal(t, ErrShortBuffer, pe.Err)
+		}
+	}
+
+	lenient := Package{AllowMissingSFRCS: true}
+	if _, err := lenient.Decode(noCrc); assert.NoError(t, err) {
+		assert.Len(t, lenient.Warnings, 1)
+		assert.Equal(t, uint16(0), lenient.ServicesFrameDataCheckSum)
+		rec := (*lenient.ServicesFrameData.(*ServiceDataSet))[0]
+		assert.Equal(t, uint16(97), rec.RecordNumber)
+	}
+
+	// при повторном использовании пакета предупреждения предыдущего разбора не сохраняются
+	if _, err := lenient.Decode(egtsPkgPosDataBytes); assert.NoError(t, err) {
+		assert.Empty(t, lenient.Warnings)
+	}
+
+	strict := Package{Strict: true, AllowMissingSFRCS: true}
+	_, err = strict.Decode(noCrc)
+	assert.Error(t, err)
+
+	// неполная сумма по-прежнему считается ошибкой
+	_, err = (&Package{AllowMissingSFRCS: true}).Decode(egtsPkgPosDataBytes[:len(egtsPkgPosDataBytes)-1])
+	assert.Error(t, err)
+}
+
+func TestPackage_EncodeFrameDataCheckSum(t *testing.T) {
+	pkg := NewResponsePackage(0x0A0B, 0x1234, egtsPcOk, nil)
+	pkg.Route, pkg.Priority = "1", "01"
+	pkg.PeerAddress, pkg.RecipientAddress, pkg.TimeToLive = 0x0102, 0x0304, 5
+	pkg.HeaderLength = 0
+
+	pkgBytes, err := pkg.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, goldenRoutedResponseBytes, pkgBytes)
+		assert.Equal(t, uint16(0xB0E8), pkg.ServicesFrameDataCheckSum)
+	}
+
+	// без SFRD поле SFRCS не передается
+	headerOnly := Package{ProtocolVersion: 1, Prefix: "00", Route: "0", EncryptionAlg: "00", Compression: "0",
+		PacketType: PtAppdataPacket, ServicesFrameDataCheckSum: 0xFFFF}
+	pkgBytes, err = headerOnly.Encode()
+	if assert.NoError(t, err) {
+		assert.Len(t, pkgBytes, DEFAULT_HEADER_LEN)
+		assert.Equal(t, uint16(0), headerOnly.ServicesFrameDataCheckSum)
+	}
+}
+
+// oversizedFrame секция данных, длина которой не помещается в FDL
+type oversizedFrame struct{}
+
+func (oversizedFrame) Decode([]byte) error     { return nil }
+func (oversizedFrame) Encode() ([]byte, error) { return make([]byte, 70000), nil }
+func (oversizedFrame) Length() uint16          { return 0 }
+
+func TestPackage_EncodeRoundTrip(t *testing.T) {
+	for _, captured := range [][]byte{
+		egtsPkgPosDataBytes,
+		testEgtsPkgBytes,
+		goldenRoutedResponseBytes,
+		testEgtsSrTermIdentityPkgBin,
+	} {
+		pkg := Package{}
+		if _, err := pkg.Decode(captured); !assert.NoError(t, err) {
+			continue
+		}
+
+		// FDL пересчитывается по фактической длине SFRD
+		pkg.FrameDataLength = 0
+		pkgBytes, err := pkg.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, captured, pkgBytes)
+		}
+	}
+
+	_, err := NewAppdataPackage(1, nil).Encode()
+	assert.NoError(t, err)
+
+	oversized := NewAppdataPackage(1, nil)
+	oversized.ServicesFrameData = oversizedFrame{}
+	_, err = oversized.Encode()
+	assert.Error(t, err)
+}
+
+func TestPackage_EncodeRouting(t *testing.T) {
+	pkg := NewResponsePackage(0x0A0B, 0x1234, egtsPcOk, nil)
+	pkg.PeerAddress, pkg.RecipientAddress, pkg.TimeToLive = 0x0102, 0x0304, 5
+
+	// без маршрутизации PRA, RCA и TTL не передаются, даже если HL остался от заголовка с маршрутизацией
+	pkg.Route, pkg.HeaderLength = "0", 16
+	pkgBytes, err := pkg.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, byte(DEFAULT_HEADER_LEN), pkg.HeaderLength)
+		assert.Equal(t, byte(DEFAULT_HEADER_LEN), pkgBytes[3])
+		assert.Equal(t, []byte{0x0B, 0x0A}, pkgBytes[7:9])
+		assert.Equal(t, byte(PtResponsePacket), pkgBytes[9])
+		assert.Equal(t, []byte{0x34, 0x12, egtsPcOk}, pkgBytes[11:14])
+		assert.Len(t, pkgBytes, DEFAULT_HEADER_LEN+int(pkg.FrameDataLength)+2)
+	}
+
+	pkg.Route, pkg.HeaderLength = "1", 0
+	pkgBytes, err = pkg.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, byte(16), pkg.HeaderLength)
+		assert.Equal(t, byte(16), pkgBytes[3])
+		assert.Equal(t, byte(PtResponsePacket), pkgBytes[9])
+		assert.Equal(t, []byte{0x02, 0x01, 0x04, 0x03, 0x05}, pkgBytes[10:15])
+		assert.Equal(t, []byte{0x34, 0x12, egtsPcOk}, pkgBytes[16:19])
+		assert.Len(t, pkgBytes, 16+int(pkg.FrameDataLength)+2)
+	}
+}
+
+func TestPackage_EncodeIgnoresStaleHeaderLength(t *testing.T) {
+	for _, tt := range []struct {
+		route string
+		hl    byte
+		want  byte
+	}{
+		{"0", 0, 11},
+		{"0", 16, 11},
+		{"0", 200, 11},
+		{"1", 11, 16},
+		{"1", 3, 16},
+	} {
+		pkg := Package{ProtocolVersion: 1, Prefix: "00", Route: tt.route, EncryptionAlg: "00", Compression: "0",
+			HeaderLength: tt.hl, PacketType: PtAppdataPacket}
+
+		pkgBytes, err := pkg.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, tt.want, pkgBytes[3])
+			assert.Len(t, pkgBytes, int(tt.want))
+			assert.True(t, LooksLikeEGTS(pkgBytes))
+		}
+	}
+}
+
+func TestPackage_AcceptBadCRC(t *testing.T) {
+	badBody := corruptPosDataPkg(len(egtsPkgPosDataBytes)-1, 0x00)
+	clean := Package{}
+	_, err := clean.Decode(egtsPkgPosDataBytes)
+	assert.NoError(t, err)
+
+	pkg := Package{AcceptBadCRC: true}
+	code, err := pkg.Decode(badBody)
+	if assert.NoError(t, err) {
+		assert.Equal(t, egtsPcOk, code)
+		assert.True(t, pkg.HeaderCRCValid())
+		assert.False(t, pkg.FrameDataCRCValid())
+		assert.Len(t, pkg.Warnings, 1)
+		assert.Equal(t, clean.ServicesFrameData, pkg.ServicesFrameData)
+	}
+
+	badHeader := corruptPosDataPkg(10, 0x00)
+	pkg = Package{AcceptBadCRC: true}
+	if _, err = pkg.Decode(badHeader); assert.NoError(t, err) {
+		assert.False(t, pkg.HeaderCRCValid())
+		assert.True(t, pkg.FrameDataCRCValid())
+	}
+
+	// повторный разбор корректного пакета сбрасывает признаки
+	if _, err = pkg.Decode(egtsPkgPosDataBytes); assert.NoError(t, err) {
+		assert.True(t, pkg.HeaderCRCValid())
+		assert.True(t, pkg.FrameDataCRCValid())
+	}
+
+	// в строгом режиме и без AcceptBadCRC неверная сумма по-прежнему ошибка
+	_, err = (&Package{AcceptBadCRC: true, Strict: true}).Decode(badBody)
+	assert.Error(t, err)
+	_, err = (&Package{}).Decode(badBody)
+	assert.Error(t, err)
+}
+
+func TestCountRecords(t *testing.T) {
+	identity := testEgtsSrTermIdentity
+	pos, extPos := testEgtsSrPosData, testEgtsSrExtPosData
+	pkg := NewIdentityWithPositionPackage(1, 10, &identity, &pos)
+	sds := pkg.ServicesFrameData.(*ServiceDataSet)
+	rec := NewServiceDataRecord(12, 133552, TeledataService, RecordDataSet{
+		RecordData{SubrecordData: &pos},
+		RecordData{SubrecordData: &extPos},
+	})
+	rec.TimeFieldExists, rec.EventIDFieldExists = "1", "1"
+	*sds = append(*sds, rec)
+
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	records, subrecords, err := CountRecords(pkgBytes)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, records)
+		assert.Equal(t, 4, subrecords)
+	}
+
+	records, subrecords, err = CountRecords(goldenRoutedResponseBytes)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, records)
+		assert.Equal(t, 0, subrecords)
+	}
+
+	respBytes, err := NewResponsePackage(2, 1, egtsPcOk, ServiceDataSet{rec}).Encode()
+	if assert.NoError(t, err) {
+		records, subrecords, err = CountRecords(respBytes)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, records)
+			assert.Equal(t, 2, subrecords)
+		}
+	}
+
+	_, _, err = CountRecords(pkgBytes[:len(pkgBytes)-10])
+	assert.Error(t, err)
+}
+
+// gzipCompressor сжатие секции данных gzip для тестов
+type gzipCompressor struct{}
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func TestPackage_Compressor(t *testing.T) {
+	pos := testEgtsSrPosData
+	pkg := NewTelematicsPackage(1, 133552, pos.NavigationTime, pos.Latitude, pos.Longitude, pos.Speed)
+	plainSFRD, err := pkg.ServicesFrameData.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	pkg.Compressor = gzipCompressor{}
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// CMP = 1, FDL и SFRCS относятся к сжатым байтам
+	assert.Equal(t, byte(0x04), pkgBytes[2]&0x04)
+	hl, fdl := int(pkgBytes[3]), int(binary.LittleEndian.Uint16(pkgBytes[5:7]))
+	assert.Len(t, pkgBytes, hl+fdl+2)
+	sent := pkgBytes[hl : hl+fdl]
+	assert.NotEqual(t, plainSFRD, sent)
+	assert.Equal(t, crc16(sent), binary.LittleEndian.Uint16(pkgBytes[hl+fdl:]))
+	assert.NotEqual(t, crc16(plainSFRD), pkg.ServicesFrameDataCheckSum)
+
+	decoded := Package{Compressor: gzipCompressor{}}
+	if _, err = decoded.Decode(pkgBytes); assert.NoError(t, err) {
+		assert.Equal(t, "1", decoded.Compression)
+		assert.True(t, decoded.FrameDataCRCValid())
+		decodedSFRD, err := decoded.ServicesFrameData.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, plainSFRD, decodedSFRD)
+		}
+	}
+}
+
+func TestPackage_EncodeInvalidFlags(t *testing.T) {
+	for _, tt := range []struct {
+		field  string
+		modify func(p *Package)
+	}{
+		{"PRF", func(p *Package) { p.Prefix = "100" }},
+		{"RTE", func(p *Package) { p.Route = "2" }},
+		{"ENA", func(p *Package) { p.EncryptionAlg = "1" }},
+		{"CMP", func(p *Package) { p.Compression = "" }},
+		{"PR", func(p *Package) { p.Priority = "101" }},
+	} {
+		pkg := NewAppdataPackage(1, nil)
+		tt.modify(pkg)
+
+		_, err := pkg.Encode()
+		if assert.Error(t, err, tt.field) {
+			assert.Contains(t, err.Error(), "поля "+tt.field+":")
+		}
+	}
+}
+
+func TestPackage_Validate(t *testing.T) {
+	decoded := Package{}
+	_, err := decoded.Decode(goldenRoutedResponseBytes)
+	if assert.NoError(t, err) {
+		assert.NoError(t, decoded.Validate())
+	}
+
+	// повторно используемый пакет не сохраняет поля маршрутизации предыдущего пакета с RTE = 1
+	_, err = decoded.Decode(egtsPkgPosDataBytes)
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint16(0), decoded.PeerAddress)
+		assert.Equal(t, uint16(0), decoded.RecipientAddress)
+		assert.Equal(t, byte(0), decoded.TimeToLive)
+		assert.NoError(t, decoded.Validate())
+	}
+	assert.NoError(t, NewAppdataPackage(1, nil).Validate())
+
+	for _, tt := range []struct {
+		name   string
+		offset int
+		modify func(p *Package)
+	}{
+		{"PRV", 0, func(p *Package) { p.ProtocolVersion = 2 }},
+		{"PRF", 2, func(p *Package) { p.Prefix = "01" }},
+		{"PT", packetTypeOffset, func(p *Package) { p.PacketType = 3 }},
+		{"RTE", 2, func(p *Package) { p.Route = "" }},
+		{"PRA без RTE", 2, func(p *Package) { p.PeerAddress = 1 }},
+		{"TTL без RTE", 2, func(p *Package) { p.TimeToLive = 3 }},
+		{"HL без RTE", 3, func(p *Package) { p.HeaderLength = 16 }},
+		{"TTL = 0 при RTE", 14, func(p *Package) { p.Route, p.HeaderLength = "1", 16 }},
+		{"HL при RTE", 3, func(p *Package) { p.Route, p.TimeToLive, p.HeaderLength = "1", 5, 11 }},
+	} {
+		pkg := NewAppdataPackage(1, nil)
+		tt.modify(pkg)
+
+		err := pkg.Validate()
+		var pe *ParseError
+		if assert.True(t, errors.As(err, &pe), tt.name) {
+			assert.Equal(t, tt.offset, pe.Offset, tt.name)
+			assert.Equal(t, PcIncHeaderform, ResultCode(err), tt.name)
+		}
+	}
+}
+
+func TestPackage_SignedAppdata(t *testing.T) {
+	pos := testEgtsSrPosData
+	signature := []byte{0xDE, 0xAD, 0xBE, 0xEF, 0x01}
+
+	pkg := WrapSubrecord(TeledataService, &pos, 7)
+	pkg.PacketType = PtSignedAppdataPacket
+	pkg.Signature = signature
+
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	headerLen := int(pkg.HeaderLength)
+	assert.Equal(t, []byte{0x05, 0x00}, pkgBytes[headerLen:headerLen+2])
+	assert.Equal(t, signature, pkgBytes[headerLen+2:headerLen+7])
+
+	decoded := Package{}
+	if _, err = decoded.Decode(pkgBytes); assert.NoError(t, err) {
+		assert.Equal(t, byte(PtSignedAppdataPacket), decoded.PacketType)
+		assert.Equal(t, signature, decoded.Signature)
+
+		reencoded, err := decoded.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, pkgBytes, reencoded)
+		}
+	}
+
+	records, subrecords, err := CountRecords(pkgBytes)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, records)
+		assert.Equal(t, 1, subrecords)
+	}
+
+	// для PT = 1 подпись не передается
+	pkg.PacketType = PtAppdataPacket
+	unsigned, err := pkg.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, len(pkgBytes)-2-len(signature), len(unsigned))
+		decoded = Package{}
+		if _, err = decoded.Decode(unsigned); assert.NoError(t, err) {
+			assert.Nil(t, decoded.Signature)
+		}
+	}
+
+	pkg.PacketType = PtSignedAppdataPacket
+	pkg.Signature = make([]byte, maxSignatureLen+1)
+	_, err = pkg.Encode()
+	assert.Error(t, err)
+}
+
+func TestPackage_DefaultDeflate(t *testing.T) {
+	pos := testEgtsSrPosData
+	pkg := NewTelematicsPackage(1, 133552, pos.NavigationTime, pos.Latitude, pos.Longitude, pos.Speed)
+	plainSFRD, err := pkg.ServicesFrameData.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// CMP = 1 без Compressor: сжатие deflate
+	pkg.Compression = "1"
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	hl, fdl := int(pkgBytes[3]), int(binary.LittleEndian.Uint16(pkgBytes[5:7]))
+	sent := pkgBytes[hl : hl+fdl]
+	inflated, err := DeflateCompressor{}.Decompress(sent)
+	if assert.NoError(t, err) {
+		assert.Equal(t, plainSFRD, inflated)
+	}
+	assert.Equal(t, crc16(sent), pkg.ServicesFrameDataCheckSum)
+
+	decoded := Package{}
+	if _, err = decoded.Decode(pkgBytes); assert.NoError(t, err) {
+		decodedSFRD, err := decoded.ServicesFrameData.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, plainSFRD, decodedSFRD)
+		}
+
+		reencoded, err := decoded.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, pkgBytes, reencoded)
+		}
+	}
+
+	// без CMP секция данных передается как есть
+	plain, err := NewTelematicsPackage(1, 133552, pos.NavigationTime, pos.Latitude, pos.Longitude, pos.Speed).Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, plainSFRD, plain[hl:len(plain)-2])
+	}
+}
+
+// fixedCompressor при сжатии возвращает заданные байты независимо от секции данных
+type fixedCompressor struct {
+	compressed []byte
+}
+
+func (c fixedCompressor) Compress(data []byte) ([]byte, error) {
+	return c.compressed, nil
+}
+
+func (fixedCompressor) Decompress(data []byte) ([]byte, error) {
+	return DeflateCompressor{}.Decompress(data)
+}
+
+func TestDeflateCompressor_DecompressLimit(t *testing.T) {
+	// секция максимальной длины распаковывается
+	maxSFRD, err := DeflateCompressor{}.Compress(make([]byte, maxFrameDataLength))
+	if assert.NoError(t, err) {
+		inflated, err := DeflateCompressor{}.Decompress(maxSFRD)
+		if assert.NoError(t, err) {
+			assert.Len(t, inflated, maxFrameDataLength)
+		}
+	}
+
+	// несколько сотен байт, распаковывающихся в мегабайт
+	bomb, err := DeflateCompressor{}.Compress(make([]byte, 1<<20))
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = DeflateCompressor{}.Decompress(bomb)
+	assert.Error(t, err)
+
+	pos := testEgtsSrPosData
+	pkg := NewTelematicsPackage(1, 133552, pos.NavigationTime, pos.Latitude, pos.Longitude, pos.Speed)
+	pkg.Compressor = fixedCompressor{compressed: bomb}
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = (&Package{}).Decode(pkgBytes)
+	if assert.Error(t, err) {
+		assert.Equal(t, egtsPcIncDataform, ResultCode(err))
+	}
+}
+
+func TestPackage_EncodeHeaderAllocs(t *testing.T) {
+	pkg := Package{ProtocolVersion: 1, Prefix: "00", Route: "1", EncryptionAlg: "00", Compression: "0",
+		PacketIdentifier: 5, PacketType: PtAppdataPacket, PeerAddress: 1, RecipientAddress: 2, TimeToLive: 3}
+
+	// заголовок собирается прямо в итоговый срез
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = pkg.Encode()
+	})
+	assert.Equal(t, float64(1), allocs)
+}
+
+func BenchmarkPackage_Encode(b *testing.B) {
+	pkg := Package{}
+	if _, err := pkg.Decode(egtsPkgPosDataBytes); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = pkg.Encode()
+	}
+}
+
+func TestPackage_SetRouting(t *testing.T) {
+	pkg := Package{}
+	if _, err := pkg.Decode(egtsPkgPosDataBytes); !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "0", pkg.Route)
+
+	pkg.SetRouting(0x0102, 0x0304, 2)
+	assert.NoError(t, pkg.Validate())
+
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, byte(16), pkgBytes[3])
+	assert.Equal(t, byte(0x20), pkgBytes[2]&0x20)
+	assert.Equal(t, []byte{0x02, 0x01, 0x04, 0x03, 0x02}, pkgBytes[10:15])
+	// секция данных не меняется
+	assert.Equal(t, egtsPkgPosDataBytes[11:], pkgBytes[16:])
+
+	routed := Package{}
+	if _, err = routed.Decode(pkgBytes); assert.NoError(t, err) {
+		assert.True(t, routed.HeaderCRCValid())
+		assert.Equal(t, uint16(0x0102), routed.PeerAddress)
+		assert.Equal(t, uint16(0x0304), routed.RecipientAddress)
+		assert.Equal(t, pkg.ServicesFrameData, routed.ServicesFrameData)
+	}
+
+	// время жизни исчерпывается на втором узле
+	assert.True(t, routed.DecrementTTL())
+	assert.Equal(t, byte(1), routed.TimeToLive)
+	assert.False(t, routed.DecrementTTL())
+	assert.Equal(t, byte(0), routed.TimeToLive)
+	assert.False(t, routed.DecrementTTL())
+	assert.Equal(t, byte(0), routed.TimeToLive)
+	assert.Error(t, routed.Validate())
+}
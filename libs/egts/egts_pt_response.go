@@ -15,6 +15,11 @@ type PtResponse struct {
 
 // Decode разбирает байты в структуру подзаписи
 func (s *PtResponse) Decode(content []byte) error {
+	return s.decode(content, decodeMode{})
+}
+
+// decode разбирает ответ с учетом режима разбора пакета
+func (s *PtResponse) decode(content []byte, mode decodeMode) error {
 	var (
 		err error
 	)
@@ -22,19 +27,20 @@ func (s *PtResponse) Decode(content []byte) error {
 
 	tmpIntBuf := make([]byte, 2)
 	if _, err = buf.Read(tmpIntBuf); err != nil {
-		return fmt.Errorf("Не удалось получить идентификатор пакета из ответа: %v", err)
+		return newParseError(len(content)-buf.Len(), "Не удалось получить идентификатор пакета из ответа: %v", err)
 	}
 	s.ResponsePacketID = binary.LittleEndian.Uint16(tmpIntBuf)
 
 	if s.ProcessingResult, err = buf.ReadByte(); err != nil {
-		return fmt.Errorf("Не удалось получить код обработки: %v", err)
+		return newParseError(len(content)-buf.Len(), "Не удалось получить код обработки: %v", err)
 	}
 
 	// если имеется о сервисном уровне, так как она необязательна
 	if buf.Len() > 0 {
-		s.SDR = &ServiceDataSet{}
-		if err = s.SDR.Decode(buf.Bytes()); err != nil {
-			return err
+		sds := &ServiceDataSet{}
+		s.SDR = sds
+		if err = sds.decode(buf.Bytes(), mode); err != nil {
+			return parseErrorAt(len(content)-buf.Len(), err)
 		}
 	}
 
@@ -81,3 +87,26 @@ func (s *PtResponse) Length() uint16 {
 
 	return result
 }
+
+//IsStructuralError признак того, что платформа отклонила пакет целиком из-за ошибки формата или контрольной суммы
+//(PR != EGTS_PC_OK без подтверждений отдельных записей)
+func (s *PtResponse) IsStructuralError() bool {
+	if s.ProcessingResult == egtsPcOk || s.ProcessingResult == egtsPcInProgress {
+		return false
+	}
+
+	sdr, ok := s.SDR.(*ServiceDataSet)
+	return !ok || sdr == nil || len(*sdr) == 0
+}
+
+//ShouldRetry признак того, что отправку пакета имеет смысл повторить: пакет поврежден при передаче
+//(EGTS_PC_HEADERCRC_ERROR, EGTS_PC_DATACRC_ERROR) или платформа временно не смогла его обработать
+//(EGTS_PC_NO_ACK, EGTS_PC_IO_ERROR, EGTS_PC_NO_RES_AVAIL). Пакет, который еще обрабатывается
+//(EGTS_PC_IN_PROGRESS) или отклонен с другим, в том числе неизвестным, кодом, повторять не нужно
+func (s *PtResponse) ShouldRetry() bool {
+	switch s.ProcessingResult {
+	case egtsPcHeaderCrcError, egtsPcDatacrcError, egtsPcNoAck, egtsPcIoError, egtsPcNoResAvail:
+		return true
+	}
+	return false
+}
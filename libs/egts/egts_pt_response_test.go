@@ -45,3 +45,48 @@ func TestEgtsPkgResp_Decode(t *testing.T) {
 		assert.Equal(t, egtsPkg, egtsPkgResp)
 	}
 }
+
+func TestPtResponse_HeaderError(t *testing.T) {
+	respBytes, err := NewResponsePackage(1, 14357, egtsPcHeaderCrcError, nil).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	pkg := Package{}
+	if _, err = pkg.Decode(respBytes); !assert.NoError(t, err) {
+		return
+	}
+
+	resp := pkg.ServicesFrameData.(*PtResponse)
+	assert.Equal(t, egtsPcHeaderCrcError, resp.ProcessingResult)
+	assert.Nil(t, resp.SDR)
+	assert.True(t, resp.IsStructuralError())
+	assert.True(t, resp.ShouldRetry())
+	assert.False(t, egtsPkgResp.ServicesFrameData.(*PtResponse).IsStructuralError())
+}
+
+func TestPtResponse_ShouldRetry(t *testing.T) {
+	tests := []struct {
+		code  uint8
+		retry bool
+	}{
+		{egtsPcHeaderCrcError, true},
+		{egtsPcDatacrcError, true},
+		{egtsPcNoAck, true},
+		{egtsPcIoError, true},
+		{egtsPcNoResAvail, true},
+		{egtsPcOk, false},
+		{egtsPcInProgress, false},
+		{egtsPcUnsProtocol, false},
+		{egtsPcDecryptError, false},
+		{egtsPcIncHeaderform, false},
+		{egtsPcIncDataform, false},
+		{egtsPcInvdatalen, false},
+		{egtsPcAuthPenied, false},
+		{200, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.retry, (&PtResponse{ProcessingResult: tt.code}).ShouldRetry(), "код %d", tt.code)
+	}
+}
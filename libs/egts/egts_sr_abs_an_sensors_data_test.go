@@ -33,3 +33,26 @@ func TestEgtsSrAbsAnSensData_Decode(t *testing.T) {
 		}
 	}
 }
+
+// проверяем что рекордсет работает правильно с данным типом подзаписи
+func TestEgtsSrAbsAnSensDataRs(t *testing.T) {
+	anSensData := SrAbsAnSensData{SensorNumber: 2, Value: 0x0186A0}
+	anSensDataRDBytes := []byte{0x18, 0x04, 0x00, 0x02, 0xA0, 0x86, 0x01}
+	anSensDataRD := RecordDataSet{
+		RecordData{
+			SubrecordType:   SrAbsAnSensDataType,
+			SubrecordLength: anSensData.Length(),
+			SubrecordData:   &anSensData,
+		},
+	}
+	testStruct := RecordDataSet{}
+
+	testBytes, err := anSensDataRD.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, anSensDataRDBytes, testBytes)
+
+		if assert.NoError(t, testStruct.Decode(anSensDataRDBytes)) {
+			assert.Equal(t, anSensDataRD, testStruct)
+		}
+	}
+}
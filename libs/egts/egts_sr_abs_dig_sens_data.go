@@ -0,0 +1,44 @@
+package egts
+
+import "fmt"
+
+//SrAbsDigSensData структура подзаписи типа EGTS_SR_ABS_DIG_SENS_DATA, которая применяется абонентским
+//терминалом для передачи данных о состоянии одного дискретного входа
+type SrAbsDigSensData struct {
+	// SensorNumber номер дискретного входа (DSN), 12 бит
+	SensorNumber uint16 `json:"DSN"`
+	// State состояние дискретного входа (DSST), 4 бита
+	State uint8 `json:"DSST"`
+}
+
+//Decode разбирает байты в структуру подзаписи
+func (e *SrAbsDigSensData) Decode(content []byte) error {
+	if len(content) < int(e.Length()) {
+		return fmt.Errorf("Некорректный размер данных abs_dig_sens_data: %d", len(content))
+	}
+
+	// младший полубайт - состояние, старший - младшие 4 бита номера входа, второй байт - старшие 8 бит номера
+	e.State = content[0] & 0x0F
+	e.SensorNumber = uint16(content[1])<<4 | uint16(content[0]>>4)
+	return nil
+}
+
+//Encode преобразовывает подзапись в набор байт
+func (e *SrAbsDigSensData) Encode() ([]byte, error) {
+	if e.SensorNumber > 0x0FFF {
+		return nil, fmt.Errorf("Номер дискретного входа %d не умещается в 12 бит", e.SensorNumber)
+	}
+	if e.State > 0x0F {
+		return nil, fmt.Errorf("Состояние дискретного входа %d не умещается в 4 бита", e.State)
+	}
+
+	return []byte{
+		byte(e.SensorNumber&0x0F)<<4 | e.State,
+		byte(e.SensorNumber >> 4),
+	}, nil
+}
+
+//Length получает длинну закодированной подзаписи
+func (e *SrAbsDigSensData) Length() uint16 {
+	return 2
+}
@@ -0,0 +1,57 @@
+package egts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var (
+	srAbsDigSensDataBytes    = []byte{0x51, 0x2A}
+	testEgtsSrAbsDigSensData = SrAbsDigSensData{
+		SensorNumber: 0x2A5,
+		State:        1,
+	}
+)
+
+func TestEgtsSrAbsDigSensData_Encode(t *testing.T) {
+	data, err := testEgtsSrAbsDigSensData.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, srAbsDigSensDataBytes, data)
+	}
+
+	_, err = (&SrAbsDigSensData{SensorNumber: 0x1000}).Encode()
+	assert.Error(t, err)
+	_, err = (&SrAbsDigSensData{State: 0x10}).Encode()
+	assert.Error(t, err)
+}
+
+func TestEgtsSrAbsDigSensData_Decode(t *testing.T) {
+	digSensData := SrAbsDigSensData{}
+
+	if assert.NoError(t, digSensData.Decode(srAbsDigSensDataBytes)) {
+		assert.Equal(t, testEgtsSrAbsDigSensData, digSensData)
+	}
+	assert.Error(t, digSensData.Decode(srAbsDigSensDataBytes[:1]))
+}
+
+// проверяем что рекордсет работает правильно с данным типом подзаписи
+func TestEgtsSrAbsDigSensDataRs(t *testing.T) {
+	digSensDataRDBytes := append([]byte{0x17, 0x02, 0x00}, srAbsDigSensDataBytes...)
+	digSensDataRD := RecordDataSet{
+		RecordData{
+			SubrecordType:   SrAbsDigSensDataType,
+			SubrecordLength: testEgtsSrAbsDigSensData.Length(),
+			SubrecordData:   &testEgtsSrAbsDigSensData,
+		},
+	}
+	testStruct := RecordDataSet{}
+
+	testBytes, err := digSensDataRD.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, digSensDataRDBytes, testBytes)
+
+		if assert.NoError(t, testStruct.Decode(digSensDataRDBytes)) {
+			assert.Equal(t, digSensDataRD, testStruct)
+		}
+	}
+}
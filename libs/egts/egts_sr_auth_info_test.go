@@ -37,6 +37,7 @@ var (
 				TimeFieldExists:          "1",
 				EventIDFieldExists:       "0",
 				ObjectIDFieldExists:      "0",
+				RawFlags:                 0x0C,
 				Time:                     286365764,
 				SourceServiceType:        AuthService,
 				RecipientServiceType:     AuthService,
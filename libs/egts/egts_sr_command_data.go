@@ -0,0 +1,354 @@
+package egts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// типы команд (CT)
+const (
+	//CtComconf подтверждение о приеме, обработке или результат выполнения команды
+	CtComconf = uint8(1)
+	//CtMsgconf подтверждение о приеме, отображении и/или обработке информационного сообщения
+	CtMsgconf = uint8(2)
+	//CtMsgfrom информационное сообщение от АС
+	CtMsgfrom = uint8(3)
+	//CtMsgto информационное сообщение для вывода на устройство отображения АС
+	CtMsgto = uint8(4)
+	//CtCom команда для выполнения на АС
+	CtCom = uint8(5)
+	//CtDelcom удаление из очереди на выполнение переданной ранее команды
+	CtDelcom = uint8(6)
+	//CtSubreq дополнительный подзапрос для выполнения
+	CtSubreq = uint8(7)
+	//CtDeliv подтверждение о доставке команды или информационного сообщения
+	CtDeliv = uint8(8)
+)
+
+// типы подтверждения (CCT)
+const (
+	//CcOk успешное выполнение, положительный ответ
+	CcOk = uint8(0)
+	//CcError обработка завершилась ошибкой
+	CcError = uint8(1)
+	//CcIll команда не может быть выполнена по причине отсутствия в списке разрешенных
+	CcIll = uint8(2)
+	//CcDel команда успешно удалена
+	CcDel = uint8(3)
+	//CcNfound команда для удаления не найдена
+	CcNfound = uint8(4)
+	//CcNconf успешное выполнение, отрицательный ответ
+	CcNconf = uint8(5)
+	//CcInprog команда передана на обработку, но для ее выполнения требуется длительное время
+	CcInprog = uint8(6)
+)
+
+// действия над параметром (ACT)
+const (
+	//ActParams передача параметров команды
+	ActParams = uint8(0)
+	//ActGet запрос значения параметра
+	ActGet = uint8(1)
+	//ActSet установка значения параметра
+	ActSet = uint8(2)
+	//ActAdd добавление нового параметра
+	ActAdd = uint8(3)
+	//ActDel удаление параметра
+	ActDel = uint8(4)
+)
+
+//ReportIntervalCommandCode код параметра периода передачи навигационных данных. Код параметра
+//различается у производителей терминалов, поэтому его можно переопределить
+var ReportIntervalCommandCode = uint16(0x0205)
+
+//SrCommandData структура подзаписи типа EGTS_SR_COMMAND_DATA, которая используется для передачи
+//команд, информационных сообщений, подтверждений доставки, подтверждений выполнения команд
+type SrCommandData struct {
+	CommandType             uint8       `json:"CT"`
+	CommandConfirmationType uint8       `json:"CCT"`
+	CommandIdentifier       uint32      `json:"CID"`
+	SourceIdentifier        uint32      `json:"SID"`
+	ACFE                    string      `json:"ACFE"`
+	CHSFE                   string      `json:"CHSFE"`
+	Charset                 uint8       `json:"CHS"`
+	AuthorizationCodeLength uint8       `json:"ACL"`
+	AuthorizationCode       string      `json:"AC"`
+	CommandData             CommandData `json:"CD"`
+
+	// commandDataExists поле CD присутствовало в разобранной подзаписи
+	commandDataExists bool
+}
+
+//CommandData структура тела команды (поле CD подзаписи EGTS_SR_COMMAND_DATA)
+type CommandData struct {
+	Address     uint16 `json:"ADR"`
+	Size        uint8  `json:"SZ"`
+	Action      uint8  `json:"ACT"`
+	CommandCode uint16 `json:"CCD"`
+	Data        []byte `json:"DT"`
+}
+
+//Decode разбирает байты в структуру подзаписи
+func (c *SrCommandData) Decode(content []byte) error {
+	var (
+		err   error
+		flags byte
+	)
+	buf := bytes.NewReader(content)
+
+	if flags, err = buf.ReadByte(); err != nil {
+		return fmt.Errorf("Не удалось получить тип команды: %v", err)
+	}
+	c.CommandType = flags >> 4
+	c.CommandConfirmationType = flags & 0x0F
+
+	tmpBuf := make([]byte, 4)
+	if _, err = io.ReadFull(buf, tmpBuf); err != nil {
+		return fmt.Errorf("Не удалось получить идентификатор команды: %v", err)
+	}
+	c.CommandIdentifier = binary.LittleEndian.Uint32(tmpBuf)
+
+	if _, err = io.ReadFull(buf, tmpBuf); err != nil {
+		return fmt.Errorf("Не удалось получить идентификатор отправителя команды: %v", err)
+	}
+	c.SourceIdentifier = binary.LittleEndian.Uint32(tmpBuf)
+
+	if flags, err = buf.ReadByte(); err != nil {
+		return fmt.Errorf("Не удалось получить байт флагов command_data: %v", err)
+	}
+	flagBits := fmt.Sprintf("%08b", flags)
+	c.ACFE = flagBits[6:7]
+	c.CHSFE = flagBits[7:]
+
+	if c.CHSFE == "1" {
+		if c.Charset, err = buf.ReadByte(); err != nil {
+			return fmt.Errorf("Не удалось получить кодировку: %v", err)
+		}
+	}
+
+	if c.ACFE == "1" {
+		if c.AuthorizationCodeLength, err = buf.ReadByte(); err != nil {
+			return fmt.Errorf("Не удалось получить длину кода авторизации: %v", err)
+		}
+
+		ac := make([]byte, c.AuthorizationCodeLength)
+		if _, err = io.ReadFull(buf, ac); err != nil {
+			return fmt.Errorf("Не удалось получить код авторизации: %v", err)
+		}
+		c.AuthorizationCode = string(ac)
+	}
+
+	c.CommandData = CommandData{}
+	c.commandDataExists = buf.Len() > 0
+	if c.commandDataExists {
+		cd := make([]byte, buf.Len())
+		if _, err = io.ReadFull(buf, cd); err != nil {
+			return fmt.Errorf("Не удалось получить тело команды: %v", err)
+		}
+
+		if err = c.CommandData.Decode(cd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Encode преобразовывает подзапись в набор байт
+func (c *SrCommandData) Encode() ([]byte, error) {
+	var (
+		err    error
+		flags  uint64
+		result []byte
+	)
+	buf := new(bytes.Buffer)
+
+	if err = buf.WriteByte(c.CommandType<<4 | c.CommandConfirmationType&0x0F); err != nil {
+		return result, fmt.Errorf("Не удалось записать тип команды: %v", err)
+	}
+
+	if err = binary.Write(buf, binary.LittleEndian, c.CommandIdentifier); err != nil {
+		return result, fmt.Errorf("Не удалось записать идентификатор команды: %v", err)
+	}
+
+	if err = binary.Write(buf, binary.LittleEndian, c.SourceIdentifier); err != nil {
+		return result, fmt.Errorf("Не удалось записать идентификатор отправителя команды: %v", err)
+	}
+
+	if flags, err = strconv.ParseUint("000000"+c.ACFE+c.CHSFE, 2, 8); err != nil {
+		return result, fmt.Errorf("Не удалось сгенерировать байт флагов command_data: %v", err)
+	}
+
+	if err = buf.WriteByte(uint8(flags)); err != nil {
+		return result, fmt.Errorf("Не удалось записать байт флагов command_data: %v", err)
+	}
+
+	if c.CHSFE == "1" {
+		if err = buf.WriteByte(c.Charset); err != nil {
+			return result, fmt.Errorf("Не удалось записать кодировку: %v", err)
+		}
+	}
+
+	if c.ACFE == "1" {
+		if err = buf.WriteByte(uint8(len(c.AuthorizationCode))); err != nil {
+			return result, fmt.Errorf("Не удалось записать длину кода авторизации: %v", err)
+		}
+
+		if _, err = buf.WriteString(c.AuthorizationCode); err != nil {
+			return result, fmt.Errorf("Не удалось записать код авторизации: %v", err)
+		}
+	}
+
+	// поле CD опционально: записывается, если было в разобранной подзаписи или заполнено
+	if c.commandDataExists || !c.CommandData.isZero() {
+		cd, err := c.CommandData.Encode()
+		if err != nil {
+			return result, err
+		}
+		buf.Write(cd)
+	}
+
+	result = buf.Bytes()
+	return result, nil
+}
+
+//Length получает длинну закодированной подзаписи
+func (c *SrCommandData) Length() uint16 {
+	var result uint16
+
+	if recBytes, err := c.Encode(); err != nil {
+		result = uint16(0)
+	} else {
+		result = uint16(len(recBytes))
+	}
+
+	return result
+}
+
+//Decode разбирает байты в структуру тела команды
+func (d *CommandData) Decode(content []byte) error {
+	var (
+		err   error
+		flags byte
+	)
+	buf := bytes.NewReader(content)
+
+	tmpBuf := make([]byte, 2)
+	if _, err = io.ReadFull(buf, tmpBuf); err != nil {
+		return fmt.Errorf("Не удалось получить адрес модуля команды: %v", err)
+	}
+	d.Address = binary.LittleEndian.Uint16(tmpBuf)
+
+	if flags, err = buf.ReadByte(); err != nil {
+		return fmt.Errorf("Не удалось получить байт действия команды: %v", err)
+	}
+	d.Size = flags >> 4
+	d.Action = flags & 0x0F
+
+	if _, err = io.ReadFull(buf, tmpBuf); err != nil {
+		return fmt.Errorf("Не удалось получить код команды: %v", err)
+	}
+	d.CommandCode = binary.LittleEndian.Uint16(tmpBuf)
+
+	d.Data = nil
+	if buf.Len() > 0 {
+		d.Data = make([]byte, buf.Len())
+		if _, err = io.ReadFull(buf, d.Data); err != nil {
+			return fmt.Errorf("Не удалось получить данные команды: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// isZero признак незаполненного тела команды
+func (d *CommandData) isZero() bool {
+	return d.Address == 0 && d.Size == 0 && d.Action == 0 && d.CommandCode == 0 && len(d.Data) == 0
+}
+
+//Encode преобразовывает тело команды в набор байт
+func (d *CommandData) Encode() ([]byte, error) {
+	var (
+		err    error
+		result []byte
+	)
+	buf := new(bytes.Buffer)
+
+	if err = binary.Write(buf, binary.LittleEndian, d.Address); err != nil {
+		return result, fmt.Errorf("Не удалось записать адрес модуля команды: %v", err)
+	}
+
+	if err = buf.WriteByte(d.Size<<4 | d.Action&0x0F); err != nil {
+		return result, fmt.Errorf("Не удалось записать байт действия команды: %v", err)
+	}
+
+	if err = binary.Write(buf, binary.LittleEndian, d.CommandCode); err != nil {
+		return result, fmt.Errorf("Не удалось записать код команды: %v", err)
+	}
+
+	if _, err = buf.Write(d.Data); err != nil {
+		return result, fmt.Errorf("Не удалось записать данные команды: %v", err)
+	}
+
+	result = buf.Bytes()
+	return result, nil
+}
+
+//ReportInterval параметр команды изменения периода передачи навигационных данных
+type ReportInterval struct {
+	Seconds uint16 `json:"SEC"`
+}
+
+//Decode разбирает данные команды (поле DT) в структуру параметра
+func (r *ReportInterval) Decode(content []byte) error {
+	if len(content) < 2 {
+		return fmt.Errorf("Не удалось получить период передачи данных: длина %d", len(content))
+	}
+	r.Seconds = binary.LittleEndian.Uint16(content)
+	return nil
+}
+
+//Encode преобразовывает параметр в данные команды (поле DT)
+func (r *ReportInterval) Encode() ([]byte, error) {
+	result := make([]byte, 2)
+	binary.LittleEndian.PutUint16(result, r.Seconds)
+	return result, nil
+}
+
+//NewReportIntervalCommand формирует команду EGTS_SR_COMMAND_DATA, устанавливающую период передачи
+//навигационных данных в seconds секунд
+func NewReportIntervalCommand(cid, sid uint32, seconds uint16) *SrCommandData {
+	param := ReportInterval{Seconds: seconds}
+	data, _ := param.Encode()
+
+	return &SrCommandData{
+		CommandType:             CtCom,
+		CommandConfirmationType: CcOk,
+		CommandIdentifier:       cid,
+		SourceIdentifier:        sid,
+		ACFE:                    "0",
+		CHSFE:                   "0",
+		CommandData: CommandData{
+			Size:        uint8(len(data)),
+			Action:      ActSet,
+			CommandCode: ReportIntervalCommandCode,
+			Data:        data,
+		},
+	}
+}
+
+//ReportInterval возвращает период передачи данных, если команда устанавливает этот параметр
+func (c *SrCommandData) ReportInterval() (ReportInterval, bool) {
+	param := ReportInterval{}
+	if c.CommandData.CommandCode != ReportIntervalCommandCode || c.CommandData.Action != ActSet {
+		return param, false
+	}
+
+	if err := param.Decode(c.CommandData.Data); err != nil {
+		return param, false
+	}
+	return param, true
+}
@@ -0,0 +1,200 @@
+package egts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// код команды "возврат на базу", используется только в тестах
+const testReturnToBaseCode = uint16(0x0301)
+
+var (
+	srCommandDataBytes = []byte{0x50, 0x2A, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x02, 0x04, 0x31, 0x32,
+		0x33, 0x34, 0x00, 0x00, 0x10, 0x01, 0x03, 0x01}
+	testSrCommandData = SrCommandData{
+		CommandType:             CtCom,
+		CommandConfirmationType: CcOk,
+		CommandIdentifier:       42,
+		SourceIdentifier:        7,
+		ACFE:                    "1",
+		CHSFE:                   "0",
+		AuthorizationCodeLength: 4,
+		AuthorizationCode:       "1234",
+		CommandData: CommandData{
+			Size:        1,
+			CommandCode: testReturnToBaseCode,
+			Data:        []byte{0x01},
+		},
+		commandDataExists: true,
+	}
+)
+
+func TestEgtsSrCommandData_Encode(t *testing.T) {
+	cmdBytes, err := testSrCommandData.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, srCommandDataBytes, cmdBytes)
+	}
+}
+
+func TestEgtsSrCommandData_Decode(t *testing.T) {
+	cmd := SrCommandData{}
+	if assert.NoError(t, cmd.Decode(srCommandDataBytes)) {
+		assert.Equal(t, testSrCommandData, cmd)
+	}
+}
+
+func commandPackage(pid uint16, cmd SrCommandData) *Package {
+	return NewAppdataPackage(pid, ServiceDataSet{
+		NewServiceDataRecord(pid, 133552, CommandsService, RecordDataSet{RecordData{SubrecordData: &cmd}}),
+	})
+}
+
+func TestEgtsSrCommandData_WithoutCommandData(t *testing.T) {
+	cmd := SrCommandData{
+		CommandType:             CtComconf,
+		CommandConfirmationType: CcOk,
+		CommandIdentifier:       42,
+		SourceIdentifier:        7,
+		ACFE:                    "0",
+		CHSFE:                   "0",
+	}
+	cmdBytes, err := cmd.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []byte{0x10, 0x2A, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x00}, cmdBytes)
+	assert.Equal(t, uint16(len(cmdBytes)), cmd.Length())
+
+	decoded := SrCommandData{}
+	if assert.NoError(t, decoded.Decode(cmdBytes)) {
+		assert.Equal(t, cmd, decoded)
+	}
+
+	// присутствовавшее в подзаписи нулевое поле CD сохраняется при повторном кодировании
+	withZeroCD := append(cmdBytes, 0x00, 0x00, 0x00, 0x00, 0x00)
+	if assert.NoError(t, decoded.Decode(withZeroCD)) {
+		reencoded, err := decoded.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, withZeroCD, reencoded)
+		}
+		assert.Equal(t, uint16(len(withZeroCD)), decoded.Length())
+	}
+}
+
+func TestEgtsSrCommandData_Truncated(t *testing.T) {
+	// длина 15 - подзапись без поля CD, 20 - поле CD без данных команды
+	for i := 1; i < len(srCommandDataBytes)-1; i++ {
+		if i == 15 {
+			continue
+		}
+		cmd := SrCommandData{}
+		assert.Error(t, cmd.Decode(srCommandDataBytes[:i]), "длина %d", i)
+	}
+}
+
+// receivedCommands возвращает подзаписи EGTS_SR_COMMAND_DATA записи rec
+func receivedCommands(rec *ServiceDataRecord) []SrCommandData {
+	cmds := []SrCommandData{}
+	for _, subRec := range rec.RecordDataSet {
+		if cmd, ok := subRec.SubrecordData.(*SrCommandData); ok {
+			cmds = append(cmds, *cmd)
+		}
+	}
+	return cmds
+}
+
+func TestEgtsSrCommandData_ReturnToBaseExchange(t *testing.T) {
+	// терминал принимает команду платформы
+	commands := make(chan SrCommandData, 1)
+	terminal := startTestServer(&Server{
+		Handler: func(rec *ServiceDataRecord) (uint8, Directive) {
+			for _, cmd := range receivedCommands(rec) {
+				commands <- cmd
+			}
+			return egtsPcOk, Continue
+		},
+	})
+	defer terminal.Close()
+
+	acks, err := NewClient(terminal).SendBatch([]*Package{commandPackage(1, testSrCommandData)})
+	if !assert.NoError(t, err) {
+		return
+	}
+	if resp, ok := acks[1]; assert.True(t, ok) {
+		assert.Equal(t, egtsPcOk, resp.ProcessingResult)
+	}
+
+	var cmd SrCommandData
+	select {
+	case cmd = <-commands:
+	default:
+		t.Fatal("терминал не получил команду")
+	}
+	assert.Equal(t, CtCom, cmd.CommandType)
+	assert.Equal(t, testReturnToBaseCode, cmd.CommandData.CommandCode)
+
+	// платформа принимает подтверждение выполнения команды от терминала
+	confirmations := make(chan SrCommandData, 1)
+	platform := startTestServer(&Server{
+		Handler: func(rec *ServiceDataRecord) (uint8, Directive) {
+			assert.Equal(t, byte(CommandsService), rec.SourceServiceType)
+			for _, conf := range receivedCommands(rec) {
+				confirmations <- conf
+			}
+			return egtsPcOk, Continue
+		},
+	})
+	defer platform.Close()
+
+	conf := SrCommandData{
+		CommandType:             CtComconf,
+		CommandConfirmationType: CcOk,
+		CommandIdentifier:       cmd.CommandIdentifier,
+		SourceIdentifier:        cmd.SourceIdentifier,
+		ACFE:                    "0",
+		CHSFE:                   "0",
+		CommandData: CommandData{
+			CommandCode: cmd.CommandData.CommandCode,
+		},
+	}
+	acks, err = NewClient(platform).SendBatch([]*Package{commandPackage(2, conf)})
+	if !assert.NoError(t, err) {
+		return
+	}
+	if resp, ok := acks[2]; assert.True(t, ok) {
+		assert.Equal(t, egtsPcOk, resp.ProcessingResult)
+	}
+
+	select {
+	case received := <-confirmations:
+		assert.Equal(t, CtComconf, received.CommandType)
+		assert.Equal(t, CcOk, received.CommandConfirmationType)
+		assert.Equal(t, testSrCommandData.CommandIdentifier, received.CommandIdentifier)
+		assert.Equal(t, testReturnToBaseCode, received.CommandData.CommandCode)
+	default:
+		t.Fatal("платформа не получила подтверждение")
+	}
+}
+
+func TestNewReportIntervalCommand(t *testing.T) {
+	pkgBytes, err := commandPackage(1, *NewReportIntervalCommand(43, 7, 30)).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	pkg := Package{}
+	if _, err = pkg.Decode(pkgBytes); !assert.NoError(t, err) {
+		return
+	}
+
+	cmd := (*pkg.ServicesFrameData.(*ServiceDataSet))[0].RecordDataSet[0].SubrecordData.(*SrCommandData)
+	assert.Equal(t, []byte{0x1E, 0x00}, cmd.CommandData.Data)
+
+	interval, ok := cmd.ReportInterval()
+	if assert.True(t, ok) {
+		assert.Equal(t, uint16(30), interval.Seconds)
+	}
+
+	_, ok = testSrCommandData.ReportInterval()
+	assert.False(t, ok)
+}
@@ -63,3 +63,55 @@ func TestEgtsSrCountersDataRs(t *testing.T) {
 		}
 	}
 }
+
+func TestEgtsSrCountersData_EmptyMask(t *testing.T) {
+	empty := SrCountersData{
+		CounterFieldExists1: "0",
+		CounterFieldExists2: "0",
+		CounterFieldExists3: "0",
+		CounterFieldExists4: "0",
+		CounterFieldExists5: "0",
+		CounterFieldExists6: "0",
+		CounterFieldExists7: "0",
+		CounterFieldExists8: "0",
+	}
+
+	countersBytes, err := empty.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte{0x00}, countersBytes)
+	}
+
+	countersData := SrCountersData{}
+	if assert.NoError(t, countersData.Decode([]byte{0x00})) {
+		assert.Equal(t, empty, countersData)
+	}
+}
+
+func TestEgtsSrCountersData_NonContiguous(t *testing.T) {
+	sparse := SrCountersData{
+		CounterFieldExists1: "0",
+		CounterFieldExists2: "1",
+		CounterFieldExists3: "0",
+		CounterFieldExists4: "0",
+		CounterFieldExists5: "1",
+		CounterFieldExists6: "0",
+		CounterFieldExists7: "0",
+		CounterFieldExists8: "0",
+		Counter2:            0x010203,
+		Counter5:            0xFFFFFF,
+	}
+	sparseBytes := []byte{0x12, 0x03, 0x02, 0x01, 0xFF, 0xFF, 0xFF}
+
+	countersBytes, err := sparse.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, sparseBytes, countersBytes)
+	}
+
+	countersData := SrCountersData{}
+	if assert.NoError(t, countersData.Decode(sparseBytes)) {
+		assert.Equal(t, sparse, countersData)
+	}
+
+	// маска заявляет больше счетчиков, чем передано
+	assert.Error(t, (&SrCountersData{}).Decode(sparseBytes[:5]))
+}
@@ -34,6 +34,7 @@ var (
 				TimeFieldExists:          "0",
 				EventIDFieldExists:       "0",
 				ObjectIDFieldExists:      "0",
+				RawFlags:                 0x98,
 				SourceServiceType:        0x01,
 				RecipientServiceType:     0x01,
 				RecordDataSet: RecordDataSet{
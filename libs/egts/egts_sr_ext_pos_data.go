@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"strconv"
 )
 
@@ -44,21 +45,21 @@ func (e *SrExtPosData) Decode(content []byte) error {
 	e.VdopFieldExists = flagBits[7:]
 
 	if e.VdopFieldExists == "1" {
-		if _, err = buf.Read(tmpBuf); err != nil {
+		if _, err = io.ReadFull(buf, tmpBuf); err != nil {
 			return fmt.Errorf("Не удалось получить снижение точности в вертикальной плоскости: %v", err)
 		}
 		e.VerticalDilutionOfPrecision = binary.LittleEndian.Uint16(tmpBuf)
 	}
 
 	if e.HdopFieldExists == "1" {
-		if _, err = buf.Read(tmpBuf); err != nil {
+		if _, err = io.ReadFull(buf, tmpBuf); err != nil {
 			return fmt.Errorf("Не удалось получить снижение точности в горизонтальной плоскости: %v", err)
 		}
 		e.HorizontalDilutionOfPrecision = binary.LittleEndian.Uint16(tmpBuf)
 	}
 
 	if e.PdopFieldExists == "1" {
-		if _, err = buf.Read(tmpBuf); err != nil {
+		if _, err = io.ReadFull(buf, tmpBuf); err != nil {
 			return fmt.Errorf("Не удалось получить снижение точности по местоположению: %v", err)
 		}
 		e.PositionDilutionOfPrecision = binary.LittleEndian.Uint16(tmpBuf)
@@ -71,7 +72,7 @@ func (e *SrExtPosData) Decode(content []byte) error {
 	}
 
 	if e.NavigationSystemFieldExists == "1" {
-		if _, err = buf.Read(tmpBuf); err != nil {
+		if _, err = io.ReadFull(buf, tmpBuf); err != nil {
 			return fmt.Errorf("Не удалось получить битовые флаги спутниковых систем: %v", err)
 		}
 		e.NavigationSystem = binary.LittleEndian.Uint16(tmpBuf)
@@ -147,3 +148,89 @@ func (e *SrExtPosData) Length() uint16 {
 
 	return result
 }
+
+//DefaultMinSatellites минимальное количество видимых спутников, при котором навигационное решение
+//считается достоверным, если порог не задан явно
+var DefaultMinSatellites = 4
+
+//SatelliteCount возвращает количество видимых спутников и признак наличия этого поля в подзаписи
+func (e *SrExtPosData) SatelliteCount() (uint8, bool) {
+	return e.Satellites, e.SatellitesFieldExists == "1"
+}
+
+//HasReliableFix проверяет, что количество видимых спутников не меньше minSats. Если minSats не больше 0,
+//используется DefaultMinSatellites. При отсутствии поля SAT решение считается недостоверным
+func (e *SrExtPosData) HasReliableFix(minSats int) bool {
+	if minSats <= 0 {
+		minSats = DefaultMinSatellites
+	}
+
+	sats, ok := e.SatelliteCount()
+	return ok && int(sats) >= minSats
+}
+
+// значения снижения точности передаются в десятых долях
+const dopScale = 10.0
+
+//HDOP возвращает значение снижения точности в горизонтальной плоскости (поле HDOP передается в 0.1 единицы)
+func (e *SrExtPosData) HDOP() float64 {
+	return float64(e.HorizontalDilutionOfPrecision) / dopScale
+}
+
+//PDOP возвращает значение снижения точности по местоположению (поле PDOP передается в 0.1 единицы)
+func (e *SrExtPosData) PDOP() float64 {
+	return float64(e.PositionDilutionOfPrecision) / dopScale
+}
+
+//VDOP возвращает значение снижения точности в вертикальной плоскости (поле VDOP передается в 0.1 единицы)
+func (e *SrExtPosData) VDOP() float64 {
+	return float64(e.VerticalDilutionOfPrecision) / dopScale
+}
+
+// битовые флаги спутниковых навигационных систем (поле NS)
+const (
+	//NsUndefined система не определена
+	NsUndefined = uint16(0)
+	//NsGlonass ГЛОНАСС
+	NsGlonass = uint16(1 << 0)
+	//NsGps GPS
+	NsGps = uint16(1 << 1)
+	//NsGalileo Galileo
+	NsGalileo = uint16(1 << 2)
+	//NsCompass Compass
+	NsCompass = uint16(1 << 3)
+	//NsBeidou Beidou
+	NsBeidou = uint16(1 << 4)
+	//NsDoris DORIS
+	NsDoris = uint16(1 << 5)
+	//NsIrnss IRNSS
+	NsIrnss = uint16(1 << 6)
+	//NsQzss QZSS
+	NsQzss = uint16(1 << 7)
+)
+
+//PrimaryNavigationSystem возвращает основную навигационную систему решения: младший установленный бит NS
+//(приоритет у ГЛОНАСС, затем GPS и т.д.). Если поле NS не передано или система не определена - NsUndefined
+func (e *SrExtPosData) PrimaryNavigationSystem() uint16 {
+	if e.NavigationSystemFieldExists != "1" {
+		return NsUndefined
+	}
+	return e.NavigationSystem & -e.NavigationSystem
+}
+
+//UsesOnlyNavigationSystems проверяет, что решение получено только с использованием систем из маски ns
+func (e *SrExtPosData) UsesOnlyNavigationSystems(ns uint16) bool {
+	return e.NavigationSystemFieldExists == "1" && e.NavigationSystem != NsUndefined && e.NavigationSystem&^ns == 0
+}
+
+//FilterByNavigationSystems возвращает отметки, решение которых получено только с использованием систем из маски ns,
+//например только ГЛОНАСС для передачи в государственные системы
+func FilterByNavigationSystems(fixes []SrExtPosData, ns uint16) []SrExtPosData {
+	result := make([]SrExtPosData, 0, len(fixes))
+	for _, fix := range fixes {
+		if fix.UsesOnlyNavigationSystems(ns) {
+			result = append(result, fix)
+		}
+	}
+	return result
+}
@@ -54,3 +54,90 @@ func TestEgtsSrExtPosDataRs(t *testing.T) {
 		}
 	}
 }
+
+func TestEgtsSrExtPosData_HasReliableFix(t *testing.T) {
+	extPosData := SrExtPosData{SatellitesFieldExists: "1", Satellites: 3}
+
+	sats, ok := extPosData.SatelliteCount()
+	assert.True(t, ok)
+	assert.Equal(t, uint8(3), sats)
+	assert.False(t, extPosData.HasReliableFix(0))
+	assert.True(t, extPosData.HasReliableFix(3))
+
+	assert.True(t, testEgtsSrExtPosData.HasReliableFix(0))
+
+	extPosData = SrExtPosData{SatellitesFieldExists: "0"}
+	_, ok = extPosData.SatelliteCount()
+	assert.False(t, ok)
+	assert.False(t, extPosData.HasReliableFix(1))
+}
+
+func TestFilterByNavigationSystems(t *testing.T) {
+	glonass := SrExtPosData{NavigationSystemFieldExists: "1", NavigationSystem: NsGlonass}
+	mixed := SrExtPosData{NavigationSystemFieldExists: "1", NavigationSystem: NsGlonass | NsGps}
+	gps := SrExtPosData{NavigationSystemFieldExists: "1", NavigationSystem: NsGps}
+	unknown := SrExtPosData{NavigationSystemFieldExists: "0"}
+
+	assert.Equal(t, NsGlonass, glonass.PrimaryNavigationSystem())
+	assert.Equal(t, NsGlonass, mixed.PrimaryNavigationSystem())
+	assert.Equal(t, NsGps, gps.PrimaryNavigationSystem())
+	assert.Equal(t, NsUndefined, unknown.PrimaryNavigationSystem())
+
+	batch := []SrExtPosData{glonass, mixed, gps, unknown}
+	assert.Equal(t, []SrExtPosData{glonass}, FilterByNavigationSystems(batch, NsGlonass))
+	assert.Equal(t, []SrExtPosData{glonass, mixed, gps}, FilterByNavigationSystems(batch, NsGlonass|NsGps))
+}
+
+func TestEgtsSrExtPosData_DOP(t *testing.T) {
+	extPosData := SrExtPosData{}
+	if assert.NoError(t, extPosData.Decode([]byte{0x07, 0x0F, 0x00, 0x0C, 0x00, 0x19, 0x00})) {
+		assert.InDelta(t, 1.5, extPosData.VDOP(), 1e-9)
+		assert.InDelta(t, 1.2, extPosData.HDOP(), 1e-9)
+		assert.InDelta(t, 2.5, extPosData.PDOP(), 1e-9)
+	}
+	assert.InDelta(t, 5.0, testEgtsSrExtPosData.HDOP(), 1e-9)
+}
+
+func TestEgtsSrExtPosData_RoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		bytes []byte
+		data  SrExtPosData
+	}{
+		{
+			name:  "без необязательных полей",
+			bytes: []byte{0x00},
+			data: SrExtPosData{NavigationSystemFieldExists: "0", SatellitesFieldExists: "0", PdopFieldExists: "0",
+				HdopFieldExists: "0", VdopFieldExists: "0"},
+		},
+		{
+			name:  "все поля",
+			bytes: []byte{0x1F, 0x0F, 0x00, 0x0C, 0x00, 0x19, 0x00, 0x09, 0x03, 0x00},
+			data: SrExtPosData{NavigationSystemFieldExists: "1", SatellitesFieldExists: "1", PdopFieldExists: "1",
+				HdopFieldExists: "1", VdopFieldExists: "1", VerticalDilutionOfPrecision: 15,
+				HorizontalDilutionOfPrecision: 12, PositionDilutionOfPrecision: 25, Satellites: 9,
+				NavigationSystem: NsGlonass | NsGps},
+		},
+		{
+			name:  "HDOP и спутники",
+			bytes: []byte{0x0A, 0x0C, 0x00, 0x07},
+			data: SrExtPosData{NavigationSystemFieldExists: "0", SatellitesFieldExists: "1", PdopFieldExists: "0",
+				HdopFieldExists: "1", VdopFieldExists: "0", HorizontalDilutionOfPrecision: 12, Satellites: 7},
+		},
+	} {
+		encoded, err := tt.data.Encode()
+		if assert.NoError(t, err, tt.name) {
+			assert.Equal(t, tt.bytes, encoded, tt.name)
+			assert.Equal(t, uint16(len(tt.bytes)), tt.data.Length(), tt.name)
+		}
+
+		decoded := SrExtPosData{}
+		if assert.NoError(t, decoded.Decode(tt.bytes), tt.name) {
+			assert.Equal(t, tt.data, decoded, tt.name)
+		}
+	}
+
+	// поле, указанное флагами, но не переданное полностью
+	assert.Error(t, (&SrExtPosData{}).Decode([]byte{0x1F, 0x0F, 0x00, 0x0C}))
+	assert.Error(t, (&SrExtPosData{}).Decode([]byte{0x01, 0x0F}))
+}
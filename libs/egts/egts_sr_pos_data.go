@@ -4,12 +4,38 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"strconv"
 	"time"
 )
 
+// navTimeEpoch начало отсчета времени навигации (NTM): 00:00:00 01.01.2010 UTC
+var navTimeEpoch = time.Date(2010, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+//NavTimeToTime преобразует время навигации NTM (количество секунд с 00:00:00 01.01.2010 UTC) в time.Time
+func NavTimeToTime(ntm uint32) time.Time {
+	return navTimeEpoch.Add(time.Duration(ntm) * time.Second)
+}
+
+//TimeToNavTime преобразует время в формат NTM (количество секунд с 00:00:00 01.01.2010 UTC). Доли секунды
+//отбрасываются, время до начала отсчета или не умещающееся в 32 бита считается ошибкой
+func TimeToNavTime(t time.Time) (uint32, error) {
+	if t.Before(navTimeEpoch) {
+		return 0, fmt.Errorf("Время %s раньше начала отсчета времени навигации", t.UTC().Format(time.RFC3339))
+	}
+
+	seconds := t.Sub(navTimeEpoch) / time.Second
+	if seconds > math.MaxUint32 {
+		return 0, fmt.Errorf("Время %s превышает максимальное значение времени навигации", t.UTC().Format(time.RFC3339))
+	}
+	return uint32(seconds), nil
+}
+
 //SrPosData структура подзаписи типа EGTS_SR_POS_DATA, которая используется абонентским
-//терминалом при передаче основных данных определения местоположения
+//терминалом при передаче основных данных определения местоположения. Незаполненные битовые флаги
+//кодируются как 0, поэтому нулевое значение SrPosData{} - недостоверная отметка (VLD = 0), а не точка (0, 0).
+//Незаполненное время (нулевое time.Time) кодируется как NTM = 0, заданное время до 01.01.2010 UTC
+//считается ошибкой
 type SrPosData struct {
 	NavigationTime      time.Time `json:"NTM"`
 	Latitude            float64   `json:"LAT"`
@@ -31,6 +57,18 @@ type SrPosData struct {
 	Source              byte      `json:"SRC"`
 	Altitude            []byte    `json:"ALT"`
 	SourceData          int16     `json:"SRCD"`
+
+	// SourceDataExists признак передачи SRCD. Выставляется при разборе, чтобы при кодировании сохранить
+	// переданное нулевое значение SRCD; ненулевое SourceData кодируется в любом случае
+	SourceDataExists bool `json:"SRCDE,omitempty"`
+
+	// GridX, GridY координаты отметки в проекции, заданной RegisterProjector. Заполняются при разборе,
+	// при кодировании не используются
+	GridX float64 `json:"X,omitempty"`
+	GridY float64 `json:"Y,omitempty"`
+
+	// rawSpeed скорость в том виде, в каком она передана (0,1 км/ч), чтобы не терять десятые доли
+	rawSpeed uint16
 }
 
 //Decode разбирает байты в структуру подзаписи
@@ -42,14 +80,12 @@ func (e *SrPosData) Decode(content []byte) error {
 	)
 	buf := bytes.NewReader(content)
 
-	// Преобразуем время навигации к формату, который требует стандарт: количество секунд с 00:00:00 01.01.2010 UTC
-	startDate := time.Date(2010, time.January, 1, 0, 0, 0, 0, time.UTC)
 	tmpUint32Buf := make([]byte, 4)
 	if _, err = buf.Read(tmpUint32Buf); err != nil {
 		return fmt.Errorf("Не удалось получить время навигации: %v", err)
 	}
 	preFieldVal := binary.LittleEndian.Uint32(tmpUint32Buf)
-	e.NavigationTime = startDate.Add(time.Duration(preFieldVal) * time.Second)
+	e.NavigationTime = NavTimeToTime(preFieldVal)
 
 	// В протоколе значение хранится в виде: широта по модулю, градусы/90*0xFFFFFFFF  и взята целая часть
 	if _, err = buf.Read(tmpUint32Buf); err != nil {
@@ -57,14 +93,16 @@ func (e *SrPosData) Decode(content []byte) error {
 	}
 
 	preFieldVal = binary.LittleEndian.Uint32(tmpUint32Buf)
-	e.Latitude = float64(float64(preFieldVal) * 90 / 0xFFFFFFFF)
+	e.Latitude = DecodeLatitude(preFieldVal, false)
+	zeroLatitude := preFieldVal == 0
 
 	// В протоколе значение хранится в виде: долгота по модулю, градусы/180*0xFFFFFFFF  и взята целая часть
 	if _, err = buf.Read(tmpUint32Buf); err != nil {
 		return fmt.Errorf("Не удалось получить время долгату: %v", err)
 	}
 	preFieldVal = binary.LittleEndian.Uint32(tmpUint32Buf)
-	e.Longitude = float64(float64(preFieldVal) * 180 / 0xFFFFFFFF)
+	e.Longitude = DecodeLongitude(preFieldVal, false)
+	zeroLongitude := preFieldVal == 0
 
 	//байт флагов
 	if flags, err = buf.ReadByte(); err != nil {
@@ -80,6 +118,14 @@ func (e *SrPosData) Decode(content []byte) error {
 	e.FIX = flagBits[6:7]
 	e.VLD = flagBits[7:]
 
+	// при нулевой широте или долготе полушарие не определено, поэтому всегда считаем его северным/восточным
+	if zeroLatitude {
+		e.LAHS = "0"
+	}
+	if zeroLongitude {
+		e.LOHS = "0"
+	}
+
 	// скорость
 	tmpUint16Buf := make([]byte, 2)
 	if _, err = buf.Read(tmpUint16Buf); err != nil {
@@ -95,7 +141,8 @@ func (e *SrPosData) Decode(content []byte) error {
 	}
 
 	// т.к. скорость с дискретностью 0,1 км
-	e.Speed = uint16(speed) / 10
+	e.rawSpeed = uint16(speed)
+	e.Speed = e.rawSpeed / 10
 
 	if e.Direction, err = buf.ReadByte(); err != nil {
 		return fmt.Errorf("Не удалось получить направление движения: %v", err)
@@ -123,80 +170,105 @@ func (e *SrPosData) Decode(content []byte) error {
 		e.Altitude = bytesTmpBuf
 	}
 
-	//TODO: разобраться с разбором SourceData
+	// данные, характеризующие источник, передаются только для части событий и определяются по длине подзаписи
+	e.SourceDataExists = buf.Len() >= 2
+	if e.SourceDataExists {
+		if _, err = buf.Read(tmpUint16Buf); err != nil {
+			return fmt.Errorf("Не удалось получить данные, характеризующие источник (событие): %v", err)
+		}
+		e.SourceData = int16(binary.LittleEndian.Uint16(tmpUint16Buf))
+	}
+
+	e.GridX, e.GridY = currentProjector().Project(signedCoordinates(e))
+
 	return err
 }
 
 //Encode преобразовывает подзапись в набор байт
 func (e *SrPosData) Encode() ([]byte, error) {
-	var (
-		err    error
-		flags  uint64
-		result []byte
-	)
-
-	buf := new(bytes.Buffer)
-	// Преобразуем время навигации к формату, который требует стандарт: количество секунд с 00:00:00 01.01.2010 UTC
-	startDate := time.Date(2010, time.January, 1, 0, 0, 0, 0, time.UTC)
-	if err = binary.Write(buf, binary.LittleEndian, uint32(e.NavigationTime.Sub(startDate).Seconds())); err != nil {
-		return result, fmt.Errorf("Не удалось записать время навигации: %v", err)
+	result, err := e.AppendTo(make([]byte, 0, 26))
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	// В протоколе значение хранится в виде: широта по модулю, градусы/90*0xFFFFFFFF  и взята целая часть
-	if err = binary.Write(buf, binary.LittleEndian, uint32(e.Latitude/90*0xFFFFFFFF)); err != nil {
-		return result, fmt.Errorf("Не удалось записать широту: %v", err)
+//AppendTo дописывает закодированную подзапись в конец dst и возвращает расширенный срез. Если емкости dst
+//достаточно, память не выделяется, что позволяет переиспользовать буфер при массовом кодировании
+func (e *SrPosData) AppendTo(dst []byte) ([]byte, error) {
+	ntm := uint32(0)
+	if !e.NavigationTime.IsZero() {
+		var err error
+		if ntm, err = TimeToNavTime(e.NavigationTime); err != nil {
+			return dst, err
+		}
 	}
+	dst = appendUint32(dst, ntm)
 
-	// В протоколе значение хранится в виде: долгота по модулю, градусы/180*0xFFFFFFFF  и взята целая часть
-	if err = binary.Write(buf, binary.LittleEndian, uint32(e.Longitude/180*0xFFFFFFFF)); err != nil {
-		return result, fmt.Errorf("Не удалось записать долготу: %v", err)
-	}
+	// В протоколе значение хранится в виде: широта по модулю, градусы/90*0xFFFFFFFF, округленное до целого.
+	// Отрицательное значение широты считается южной широтой
+	lat, south := EncodeLatitude(e.Latitude)
+	dst = appendUint32(dst, lat)
 
-	//байт флагов
-	flags, err = strconv.ParseUint(e.ALTE+e.LOHS+e.LAHS+e.MV+e.BB+e.CS+e.FIX+e.VLD, 2, 8)
-	if err != nil {
-		return result, fmt.Errorf("Не удалось сгенерировать байт флагов pos_data: %v", err)
-	}
+	// В протоколе значение хранится в виде: долгота по модулю, градусы/180*0xFFFFFFFF, округленное до целого
+	lon, west := EncodeLongitude(e.Longitude)
+	dst = appendUint32(dst, lon)
 
-	if err = buf.WriteByte(uint8(flags)); err != nil {
-		return result, fmt.Errorf("Не удалось записать флаги: %v", err)
+	// на экваторе и нулевом меридиане полушарие всегда записывается как северное/восточное
+	lahs, lohs := e.LAHS, e.LOHS
+	if south {
+		lahs = "1"
 	}
-
-	// скорость
-	speed := e.Speed*10 | uint16(e.DirectionHighestBit)<<15 // 15 бит
-	speed = speed | uint16(e.AltitudeSign)<<14              //14 бит
-	spd := make([]byte, 2)
-	binary.LittleEndian.PutUint16(spd, speed)
-	if _, err = buf.Write(spd); err != nil {
-		return result, fmt.Errorf("Не удалось записать скорость: %v", err)
+	if west {
+		lohs = "1"
 	}
-
-	dir := e.Direction &^ (e.DirectionHighestBit << 7)
-	if err = binary.Write(buf, binary.LittleEndian, dir); err != nil {
-		return result, fmt.Errorf("Не удалось записать направление движения: %v", err)
+	if lat == 0 {
+		lahs = "0"
+	}
+	if lon == 0 {
+		lohs = "0"
 	}
 
-	if _, err = buf.Write(e.Odometer); err != nil {
-		return result, fmt.Errorf("Не удалось запсиать пройденное расстояние (пробег) в км: %v", err)
+	//байт флагов
+	flags := byte(0)
+	for _, bit := range [...]string{e.ALTE, lohs, lahs, e.MV, e.BB, e.CS, e.FIX, e.VLD} {
+		switch bit {
+		case "0", "":
+			flags <<= 1
+		case "1":
+			flags = flags<<1 | 1
+		default:
+			return dst, fmt.Errorf("Не удалось сгенерировать байт флагов pos_data: некорректное значение бита %q", bit)
+		}
 	}
+	dst = append(dst, flags)
 
-	if err = binary.Write(buf, binary.LittleEndian, e.DigitalInputs); err != nil {
-		return result, fmt.Errorf("Не удалось записать битовые флаги, определяют состояние основных дискретных входов: %v", err)
+	// скорость, под которую отведено 14 бит в 0,1 км/ч
+	if e.Speed > MaxPosDataSpeed {
+		return dst, fmt.Errorf("Скорость %d км/ч превышает максимальную для pos_data: %d км/ч", e.Speed, MaxPosDataSpeed)
 	}
+	speed := e.RawSpeed() | uint16(e.DirectionHighestBit)<<15 // 15 бит
+	speed = speed | uint16(e.AltitudeSign)<<14                // 14 бит
+	dst = append(dst, byte(speed), byte(speed>>8))
 
-	if err = binary.Write(buf, binary.LittleEndian, e.Source); err != nil {
-		return result, fmt.Errorf("Не удалось записать источник (событие), инициировавший посылку: %v", err)
+	dir := e.Direction &^ (e.DirectionHighestBit << 7)
+	dst = append(dst, dir)
+	if e.Odometer == nil {
+		dst = append(dst, 0, 0, 0)
+	} else {
+		dst = append(dst, e.Odometer...)
 	}
+	dst = append(dst, e.DigitalInputs, e.Source)
 
 	if e.ALTE == "1" {
-		if _, err = buf.Write(e.Altitude); err != nil {
-			return result, fmt.Errorf("Не удалось записать высоту над уровнем моря: %v", err)
-		}
+		dst = append(dst, e.Altitude...)
 	}
 
-	//TODO: разобраться с записью SourceData
-	result = buf.Bytes()
-	return result, nil
+	if e.SourceDataExists || e.SourceData != 0 {
+		dst = append(dst, byte(e.SourceData), byte(uint16(e.SourceData)>>8))
+	}
+
+	return dst, nil
 }
 
 //Length получает длинну закодированной подзаписи
@@ -211,3 +283,91 @@ func (e *SrPosData) Length() uint16 {
 
 	return result
 }
+
+//MaxPosDataSpeed максимальная скорость в км/ч, которую можно передать в поле SPD (14 бит, 0,1 км/ч)
+const MaxPosDataSpeed = 0x3FFF / 10
+
+//SrcAngleChange код источника (SRC) "превышение установленного значения угла поворота"
+const SrcAngleChange = 2
+
+// описания кодов источника (SRC) 0-16. Для остальных кодов, в том числе определенных в ГОСТ 33472 после 16,
+// описание в библиотеке не задано
+var sourceNames = map[byte]string{
+	0:              "таймер при включенном зажигании",
+	1:              "пробег заданной дистанции",
+	SrcAngleChange: "превышение установленного значения угла поворота",
+	3:              "ответ на запрос",
+	4:              "изменение состояния входа",
+	5:              "таймер при выключенном зажигании",
+	6:              "отключение периферийного оборудования",
+	7:              "превышение одного из заданных порогов скорости",
+	8:              "перезагрузка центрального процессора",
+	9:              "перегрузка по выходу",
+	10:             "сработал датчик вскрытия корпуса",
+	11:             "переход на резервное питание",
+	12:             "снижение напряжения резервного источника питания",
+	13:             "нажата тревожная кнопка",
+	14:             "запрос на установление голосовой связи с оператором",
+	15:             "экстренный вызов",
+	16:             "появление данных от внешнего сервиса",
+}
+
+//SourceName возвращает описание кода источника (SRC). Коды без описания не считаются ошибкой и
+//возвращаются в виде "unknown(n)" с сохранением числового значения
+func (e *SrPosData) SourceName() string {
+	if name, ok := sourceNames[e.Source]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", e.Source)
+}
+
+//TurnAngle возвращает угол поворота в градусах, если посылка инициирована превышением угла поворота
+func (e *SrPosData) TurnAngle() (float64, bool) {
+	if e.Source != SrcAngleChange {
+		return 0, false
+	}
+	return float64(e.SourceData), true
+}
+
+//RawSpeed возвращает скорость в единицах протокола (0,1 км/ч) - 14 бит поля SPD без DIRH и ALTS. Для
+//разобранной подзаписи значение совпадает с переданным, если Speed с тех пор не менялась
+func (e *SrPosData) RawSpeed() uint16 {
+	if e.rawSpeed/10 == e.Speed && e.rawSpeed != 0 {
+		return e.rawSpeed
+	}
+	return e.Speed * 10 & 0x3FFF
+}
+
+//FixType тип навигационного решения
+type FixType uint8
+
+const (
+	//FixNone нет достоверных навигационных данных
+	FixNone FixType = iota
+	//Fix2D двухмерное навигационное решение
+	Fix2D
+	//Fix3D трехмерное навигационное решение
+	Fix3D
+)
+
+//FixType возвращает тип навигационного решения по флагам VLD и FIX
+func (e *SrPosData) FixType() FixType {
+	if e.VLD != "1" {
+		return FixNone
+	}
+
+	if e.FIX == "1" {
+		return Fix3D
+	}
+	return Fix2D
+}
+
+//HasReliableAltitude признак того, что высоте можно доверять: поле передано и решение трехмерное
+func (e *SrPosData) HasReliableAltitude() bool {
+	return e.ALTE == "1" && e.FixType() == Fix3D
+}
+
+//IsBuffered признак того, что данные отправлены из памяти (черного ящика), а не в реальном времени
+func (e *SrPosData) IsBuffered() bool {
+	return e.BB == "1"
+}
@@ -1,7 +1,9 @@
 package egts
 
 import (
+	"encoding/binary"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 	"time"
 )
@@ -24,6 +26,7 @@ var (
 		DirectionHighestBit: 1,
 		AltitudeSign:        0,
 		Speed:               200,
+		rawSpeed:            2000,
 		Direction:           172,
 		Odometer:            []byte{0x01, 0x00, 0x00},
 		DigitalInputs:       0,
@@ -46,3 +49,219 @@ func TestEgtsSrPosData_Decode(t *testing.T) {
 		assert.Equal(t, posData, testEgtsSrPosData)
 	}
 }
+
+func TestEgtsSrPosData_FixType(t *testing.T) {
+	posData := SrPosData{VLD: "0", FIX: "1", ALTE: "1"}
+	assert.Equal(t, FixNone, posData.FixType())
+	assert.False(t, posData.HasReliableAltitude())
+
+	posData = SrPosData{VLD: "1", FIX: "0", ALTE: "1", Altitude: []byte{0x1e, 0x00, 0x00}}
+	assert.Equal(t, Fix2D, posData.FixType())
+	assert.False(t, posData.HasReliableAltitude())
+
+	posData = SrPosData{VLD: "1", FIX: "1", ALTE: "1", Altitude: []byte{0x1e, 0x00, 0x00}}
+	assert.Equal(t, Fix3D, posData.FixType())
+	assert.True(t, posData.HasReliableAltitude())
+}
+
+func TestEgtsSrPosData_ZeroCoordinatesHemisphere(t *testing.T) {
+	posData := testEgtsSrPosData
+	posData.Latitude = 0
+	posData.Longitude = 0
+	posData.LAHS = "1"
+	posData.LOHS = "1"
+
+	posDataBytes, err := posData.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	// биты LAHS и LOHS сброшены
+	assert.Equal(t, byte(0x01), posDataBytes[12])
+
+	decoded := SrPosData{}
+	if assert.NoError(t, decoded.Decode(posDataBytes)) {
+		assert.Equal(t, "0", decoded.LAHS)
+		assert.Equal(t, "0", decoded.LOHS)
+
+		lat, lon := signedCoordinates(&decoded)
+		assert.False(t, math.Signbit(lat))
+		assert.False(t, math.Signbit(lon))
+	}
+
+	// точка на экваторе в западном полушарии сохраняет знак долготы
+	posData.Longitude = 37.5
+	posDataBytes, err = posData.Encode()
+	if assert.NoError(t, err) && assert.NoError(t, decoded.Decode(posDataBytes)) {
+		assert.Equal(t, "0", decoded.LAHS)
+		assert.Equal(t, "1", decoded.LOHS)
+	}
+}
+
+func TestEgtsSrPosData_IsBuffered(t *testing.T) {
+	posData := testEgtsSrPosData
+	assert.False(t, posData.IsBuffered())
+
+	posData.BB = "1"
+	assert.True(t, posData.IsBuffered())
+}
+
+func TestEgtsSrPosData_TurnAngle(t *testing.T) {
+	angleEventBytes := append(append([]byte{}, testEgtsSrPosDataBytes...), 0x2D, 0x00)
+	angleEventBytes[20] = SrcAngleChange
+
+	posData := SrPosData{}
+	if assert.NoError(t, posData.Decode(angleEventBytes)) {
+		angle, ok := posData.TurnAngle()
+		assert.True(t, ok)
+		assert.Equal(t, float64(45), angle)
+
+		posDataBytes, err := posData.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, angleEventBytes, posDataBytes)
+		}
+	}
+
+	_, ok := testEgtsSrPosData.TurnAngle()
+	assert.False(t, ok)
+}
+
+func TestSrPosData_RawSpeed(t *testing.T) {
+	posBytes := append([]byte{}, testEgtsSrPosDataBytes...)
+	// SPD = 200,5 км/ч с установленными DIRH и ALTS
+	posBytes[13], posBytes[14] = 0xD5, 0xC7
+
+	pos := SrPosData{}
+	if !assert.NoError(t, pos.Decode(posBytes)) {
+		return
+	}
+	assert.Equal(t, uint16(2005), pos.RawSpeed())
+	assert.Equal(t, uint16(200), pos.Speed)
+	assert.Equal(t, uint8(1), pos.AltitudeSign)
+
+	encoded, err := pos.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, posBytes, encoded)
+	}
+
+	pos.Speed = 60
+	assert.Equal(t, uint16(600), pos.RawSpeed())
+}
+
+func TestSrPosData_EncodeSpeedRange(t *testing.T) {
+	pos := testEgtsSrPosData
+	pos.Speed = MaxPosDataSpeed
+	if encoded, err := pos.Encode(); assert.NoError(t, err) {
+		assert.Equal(t, uint16(16380), binary.LittleEndian.Uint16(encoded[13:15])&0x3FFF)
+	}
+
+	// скорость, не умещающаяся в 14 бит, не кодируется
+	pos.Speed = MaxPosDataSpeed + 1
+	_, err := pos.Encode()
+	assert.Error(t, err)
+}
+
+func TestSrPosData_ZeroValue(t *testing.T) {
+	zero := SrPosData{}
+	assert.Equal(t, FixNone, zero.FixType())
+
+	posBytes, err := zero.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, posBytes, len(testEgtsSrPosDataBytes))
+	// байт флагов, VLD - младший бит
+	assert.Equal(t, byte(0), posBytes[12])
+
+	decoded := SrPosData{}
+	if assert.NoError(t, decoded.Decode(posBytes)) {
+		assert.Equal(t, "0", decoded.VLD)
+		assert.Equal(t, FixNone, decoded.FixType())
+		assert.Equal(t, time.Date(2010, time.January, 1, 0, 0, 0, 0, time.UTC), decoded.NavigationTime)
+	}
+}
+
+func TestSrPosData_EncodeTimeBeforeEpoch(t *testing.T) {
+	pos := testEgtsSrPosData
+	pos.NavigationTime = time.Date(2009, time.December, 31, 23, 59, 59, 0, time.UTC)
+
+	_, err := pos.Encode()
+	assert.Error(t, err)
+}
+
+func TestSrPosData_UnknownSource(t *testing.T) {
+	posBytes := append([]byte{}, testEgtsSrPosDataBytes...)
+	// SRC - последний байт подзаписи без ALT и SRCD
+	posBytes[len(posBytes)-1] = 200
+
+	pos := SrPosData{}
+	if assert.NoError(t, pos.Decode(posBytes)) {
+		assert.Equal(t, byte(200), pos.Source)
+		assert.Equal(t, "unknown(200)", pos.SourceName())
+	}
+
+	assert.Equal(t, "таймер при включенном зажигании", testEgtsSrPosData.SourceName())
+}
+
+func TestSrPosData_DecodeCapturedCoordinates(t *testing.T) {
+	pos := SrPosData{}
+	if assert.NoError(t, pos.Decode(testEgtsSrPosDataBytes)) {
+		// LAT и LONG передаются модулем в little-endian: градусы / 90 * 0xFFFFFFFF и градусы / 180 * 0xFFFFFFFF
+		assert.Equal(t, float64(0x9E051C6F)*90/0xFFFFFFFF, pos.Latitude)
+		assert.Equal(t, float64(0x353CB57A)*180/0xFFFFFFFF, pos.Longitude)
+		assert.InDelta(t, 55.553894, pos.Latitude, 1e-6)
+		assert.InDelta(t, 37.432367, pos.Longitude, 1e-6)
+		// SPD 14 бит в 0.1 км/ч, старший бит - старший бит направления
+		assert.Equal(t, uint16(200), pos.Speed)
+		assert.Equal(t, uint8(1), pos.DirectionHighestBit)
+		assert.Equal(t, time.Date(2018, time.July, 6, 20, 8, 53, 0, time.UTC), pos.NavigationTime)
+	}
+
+	// та же отметка в южном и западном полушариях (LAHS и LOHS)
+	southWest := append([]byte{}, testEgtsSrPosDataBytes...)
+	southWest[12] |= 0x60
+	pos = SrPosData{}
+	if assert.NoError(t, pos.Decode(southWest)) {
+		assert.Equal(t, "1", pos.LAHS)
+		assert.Equal(t, "1", pos.LOHS)
+		// модули координат не меняются, знак задают флаги полушарий
+		assert.Equal(t, testEgtsSrPosData.Latitude, pos.Latitude)
+		lat, lon := signedCoordinates(&pos)
+		assert.InDelta(t, -55.553894, lat, 1e-6)
+		assert.InDelta(t, -37.432367, lon, 1e-6)
+
+		posBytes, err := pos.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, southWest, posBytes)
+		}
+	}
+}
+
+func TestNavTimeConversion(t *testing.T) {
+	epoch := time.Date(2010, time.January, 1, 0, 0, 0, 0, time.UTC)
+	known := time.Date(2018, time.July, 5, 20, 8, 53, 0, time.UTC)
+
+	assert.Equal(t, epoch, NavTimeToTime(0))
+	assert.Equal(t, known, NavTimeToTime(0x10013FD5))
+
+	for _, tt := range []struct {
+		tm   time.Time
+		want uint32
+	}{
+		{epoch, 0},
+		{epoch.Add(time.Second - time.Nanosecond), 0},
+		{epoch.Add(time.Second), 1},
+		{known, 0x10013FD5},
+		{known.In(time.FixedZone("MSK", 3*60*60)), 0x10013FD5},
+		{NavTimeToTime(math.MaxUint32), math.MaxUint32},
+	} {
+		ntm, err := TimeToNavTime(tt.tm)
+		if assert.NoError(t, err, tt.tm.String()) {
+			assert.Equal(t, tt.want, ntm, tt.tm.String())
+		}
+	}
+
+	_, err := TimeToNavTime(epoch.Add(-time.Second))
+	assert.Error(t, err)
+	_, err = TimeToNavTime(NavTimeToTime(math.MaxUint32).Add(time.Second))
+	assert.Error(t, err)
+}
@@ -34,6 +34,7 @@ var (
 					TimeFieldExists:          "0",
 					EventIDFieldExists:       "0",
 					ObjectIDFieldExists:      "0",
+					RawFlags:                 0x20,
 					SourceServiceType:        AuthService,
 					RecipientServiceType:     AuthService,
 					RecordDataSet: RecordDataSet{
@@ -31,6 +31,7 @@ var (
 				TimeFieldExists:          "0",
 				EventIDFieldExists:       "0",
 				ObjectIDFieldExists:      "0",
+				RawFlags:                 0x20,
 				SourceServiceType:        AuthService,
 				RecipientServiceType:     AuthService,
 				RecordDataSet: RecordDataSet{
@@ -67,3 +68,41 @@ func TestEgtsPkgSrResCode_Decode(t *testing.T) {
 		assert.Equal(t, egtsPkg, egtsPkgSrResCode)
 	}
 }
+
+func TestBuildAuthResponse(t *testing.T) {
+	pkg, err := BuildAuthResponse(14357, egtsPcOk, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	rec := (*pkg.ServicesFrameData.(*ServiceDataSet))[0]
+	assert.Equal(t, byte(AuthService), rec.SourceServiceType)
+	assert.Equal(t, byte(AuthService), rec.RecipientServiceType)
+
+	pkgBytes, err := pkg.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, testEgtsPkgSrResCodeBytes, pkgBytes)
+	}
+}
+
+func TestBuildAuthResponse_DispatcherIdentity(t *testing.T) {
+	dispatcher := SrDispatcherIdentity{DispatcherType: 0, DispatcherID: 1024, Description: "platform"}
+	pkg, err := BuildAuthResponse(1, egtsPcOk, &dispatcher)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	decoded := Package{}
+	if _, err = decoded.Decode(pkgBytes); assert.NoError(t, err) {
+		rds := (*decoded.ServicesFrameData.(*ServiceDataSet))[0].RecordDataSet
+		if assert.Len(t, rds, 2) {
+			assert.Equal(t, &SrResultCode{ResultCode: egtsPcOk}, rds[0].SubrecordData)
+			assert.Equal(t, &dispatcher, rds[1].SubrecordData)
+		}
+	}
+}
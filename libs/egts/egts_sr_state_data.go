@@ -19,6 +19,24 @@ type SrStateData struct {
 	BBU                    string `json:"BBU"`
 }
 
+// напряжения источников питания передаются в десятых долях вольта
+const voltageScale = 10.0
+
+//MainPowerSourceVolts возвращает напряжение основного источника питания в вольтах (поле MPSV передается в 0.1 В)
+func (e *SrStateData) MainPowerSourceVolts() float64 {
+	return float64(e.MainPowerSourceVoltage) / voltageScale
+}
+
+//BackUpBatteryVolts возвращает напряжение резервной батареи в вольтах (поле BBV передается в 0.1 В)
+func (e *SrStateData) BackUpBatteryVolts() float64 {
+	return float64(e.BackUpBatteryVoltage) / voltageScale
+}
+
+//InternalBatteryVolts возвращает напряжение внутренней батареи в вольтах (поле IBV передается в 0.1 В)
+func (e *SrStateData) InternalBatteryVolts() float64 {
+	return float64(e.InternalBatteryVoltage) / voltageScale
+}
+
 //Decode разбирает байты в структуру подзаписи
 func (e *SrStateData) Decode(content []byte) error {
 	var (
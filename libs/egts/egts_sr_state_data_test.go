@@ -55,3 +55,33 @@ func TestEgtsSrStateDataRs(t *testing.T) {
 		}
 	}
 }
+
+func TestEgtsSrStateData_DecodeCaptured(t *testing.T) {
+	// режим "Активный", питание от бортовой сети 12.6 В, резервная батарея 4.1 В, внутренняя 3.8 В,
+	// навигационный модуль включен, используется внутренняя батарея
+	captured := []byte{0x02, 0x7E, 0x29, 0x26, 0x06}
+
+	stateData := SrStateData{}
+	if assert.NoError(t, stateData.Decode(captured)) {
+		assert.Equal(t, SrStateData{
+			State:                  2,
+			MainPowerSourceVoltage: 126,
+			BackUpBatteryVoltage:   41,
+			InternalBatteryVoltage: 38,
+			NMS:                    "1",
+			IBU:                    "1",
+			BBU:                    "0",
+		}, stateData)
+		assert.InDelta(t, 12.6, stateData.MainPowerSourceVolts(), 1e-9)
+		assert.InDelta(t, 4.1, stateData.BackUpBatteryVolts(), 1e-9)
+		assert.InDelta(t, 3.8, stateData.InternalBatteryVolts(), 1e-9)
+		assert.True(t, stateData.NavigationModuleOn())
+
+		encoded, err := stateData.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, captured, encoded)
+		}
+	}
+
+	assert.Error(t, (&SrStateData{}).Decode(captured[:4]))
+}
@@ -4,9 +4,19 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"strconv"
 )
 
+// длины полей фиксированного размера EGTS_SR_TERM_IDENTITY
+const (
+	imeiLen   = 15
+	imsiLen   = 16
+	lngcLen   = 3
+	nidLen    = 3
+	msisdnLen = 15
+)
+
 //SrTermIdentity структура подзаписи типа EGTS_SR_TERM_IDENTITY, которая используется АС при запросе
 //авторизации на телематическую платформу и содержит учетные данные АС.
 type SrTermIdentity struct {
@@ -65,32 +75,26 @@ func (e *SrTermIdentity) Decode(content []byte) error {
 	}
 
 	if e.IMEIE == "1" {
-		tmpBuf = make([]byte, 15)
-		if _, err = buf.Read(tmpBuf); err != nil {
-			return fmt.Errorf("Не удалось получить IMEI при авторизации")
+		if e.IMEI, err = readFixedString(buf, imeiLen); err != nil {
+			return fmt.Errorf("Не удалось получить IMEI при авторизации: %v", err)
 		}
-		e.IMEI = string(tmpBuf)
 	}
 
 	if e.IMSIE == "1" {
-		tmpBuf = make([]byte, 16)
-		if _, err = buf.Read(tmpBuf); err != nil {
-			return fmt.Errorf("Не удалось получить IMSI при авторизации")
+		if e.IMSI, err = readFixedString(buf, imsiLen); err != nil {
+			return fmt.Errorf("Не удалось получить IMSI при авторизации: %v", err)
 		}
-		e.IMSI = string(tmpBuf)
 	}
 
 	if e.LNGCE == "1" {
-		tmpBuf = make([]byte, 3)
-		if _, err = buf.Read(tmpBuf); err != nil {
-			return fmt.Errorf("Не удалось получить код языка при авторизации")
+		if e.LanguageCode, err = readFixedString(buf, lngcLen); err != nil {
+			return fmt.Errorf("Не удалось получить код языка при авторизации: %v", err)
 		}
-		e.LanguageCode = string(tmpBuf)
 	}
 
 	if e.NIDE == "1" {
-		e.NetworkIdentifier = make([]byte, 3)
-		if _, err = buf.Read(e.NetworkIdentifier); err != nil {
+		e.NetworkIdentifier = make([]byte, nidLen)
+		if _, err = io.ReadFull(buf, e.NetworkIdentifier); err != nil {
 			return fmt.Errorf("Не удалось получить код идентификатор сети оператора при авторизации")
 		}
 	}
@@ -104,11 +108,9 @@ func (e *SrTermIdentity) Decode(content []byte) error {
 	}
 
 	if e.MNE == "1" {
-		tmpBuf = make([]byte, 15)
-		if _, err = buf.Read(tmpBuf); err != nil {
-			return fmt.Errorf("Не удалось получить телефонный номер мобильного абонента")
+		if e.MobileNumber, err = readFixedString(buf, msisdnLen); err != nil {
+			return fmt.Errorf("Не удалось получить телефонный номер мобильного абонента: %v", err)
 		}
-		e.MobileNumber = string(tmpBuf)
 	}
 
 	return err
@@ -139,25 +141,25 @@ func (e *SrTermIdentity) Encode() ([]byte, error) {
 	}
 
 	if e.IMEIE == "1" {
-		if _, err = buf.Write([]byte(e.IMEI)); err != nil {
-			return result, fmt.Errorf("Не удалось записать IMEI при авторизации")
+		if err = writeFixedString(buf, e.IMEI, imeiLen); err != nil {
+			return result, fmt.Errorf("Не удалось записать IMEI при авторизации: %v", err)
 		}
 	}
 
 	if e.IMSIE == "1" {
-		if _, err = buf.Write([]byte(e.IMSI)); err != nil {
-			return result, fmt.Errorf("Не удалось записать IMSI при авторизации")
+		if err = writeFixedString(buf, e.IMSI, imsiLen); err != nil {
+			return result, fmt.Errorf("Не удалось записать IMSI при авторизации: %v", err)
 		}
 	}
 
 	if e.LNGCE == "1" {
-		if _, err = buf.Write([]byte(e.LanguageCode)); err != nil {
-			return result, fmt.Errorf("Не удалось записать IMSI при авторизации")
+		if err = writeFixedString(buf, e.LanguageCode, lngcLen); err != nil {
+			return result, fmt.Errorf("Не удалось записать код языка при авторизации: %v", err)
 		}
 	}
 
 	if e.NIDE == "1" {
-		if _, err = buf.Write(e.NetworkIdentifier); err != nil {
+		if err = writeFixedString(buf, string(e.NetworkIdentifier), nidLen); err != nil {
 			return result, fmt.Errorf("Не удалось записать код идентификатор сети оператора при авторизации")
 		}
 	}
@@ -169,8 +171,8 @@ func (e *SrTermIdentity) Encode() ([]byte, error) {
 	}
 
 	if e.MNE == "1" {
-		if _, err = buf.Write([]byte(e.MobileNumber)); err != nil {
-			return result, fmt.Errorf("Не удалось записать телефонный номер мобильного абонента")
+		if err = writeFixedString(buf, e.MobileNumber, msisdnLen); err != nil {
+			return result, fmt.Errorf("Не удалось записать телефонный номер мобильного абонента: %v", err)
 		}
 	}
 
@@ -190,3 +192,23 @@ func (e *SrTermIdentity) Length() uint16 {
 
 	return result
 }
+
+// readFixedString считывает строковое поле фиксированной длины n. Дополняющие нулевые байты в конце отбрасываются
+func readFixedString(buf *bytes.Reader, n int) (string, error) {
+	field := make([]byte, n)
+	if _, err := io.ReadFull(buf, field); err != nil {
+		return "", err
+	}
+	return string(bytes.TrimRight(field, "\x00")), nil
+}
+
+// writeFixedString записывает строковое поле фиксированной длины n: более короткое значение дополняется нулевыми
+// байтами, более длинное считается ошибкой, так как усечение исказило бы идентификатор
+func writeFixedString(buf *bytes.Buffer, s string, n int) error {
+	if len(s) > n {
+		return fmt.Errorf("длина значения %d превышает размер поля %d", len(s), n)
+	}
+	buf.WriteString(s)
+	buf.Write(make([]byte, n-len(s)))
+	return nil
+}
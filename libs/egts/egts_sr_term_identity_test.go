@@ -45,6 +45,7 @@ var (
 				TimeFieldExists:          "0",
 				EventIDFieldExists:       "0",
 				ObjectIDFieldExists:      "1",
+				RawFlags:                 0x99,
 				ObjectIdentifier:         2,
 				SourceServiceType:        AuthService,
 				RecipientServiceType:     AuthService,
@@ -93,3 +94,48 @@ func TestEgtsSrTermIdentityPkg_Decode(t *testing.T) {
 		assert.Equal(t, srTermIdentPkg, testEgtsSrTermIdentityPkg)
 	}
 }
+
+func TestEgtsSrTermIdentity_OptionalFields(t *testing.T) {
+	imeiOnly := SrTermIdentity{TerminalIdentifier: 7, MNE: "0", BSE: "0", NIDE: "0", SSRA: "0", LNGCE: "0",
+		IMSIE: "0", IMEIE: "1", HDIDE: "0", IMEI: "356307042441013"}
+	imeiOnlyBytes := append([]byte{0x07, 0x00, 0x00, 0x00, 0x02}, "356307042441013"...)
+
+	full := SrTermIdentity{TerminalIdentifier: 7, MNE: "1", BSE: "1", NIDE: "1", SSRA: "1", LNGCE: "1",
+		IMSIE: "1", IMEIE: "1", HDIDE: "1", HomeDispatcherIdentifier: 0x0102, IMEI: "356307042441013",
+		IMSI: "250011234567890", LanguageCode: "rus", NetworkIdentifier: []byte{0x02, 0xFA, 0x01},
+		BufferSize: 1024, MobileNumber: "79161234567"}
+	fullBytes := []byte{0x07, 0x00, 0x00, 0x00, 0xFF, 0x02, 0x01}
+	fullBytes = append(fullBytes, "356307042441013"...)
+	// IMSI короче поля и дополняется нулевым байтом
+	fullBytes = append(fullBytes, "250011234567890\x00"...)
+	fullBytes = append(fullBytes, "rus"...)
+	fullBytes = append(fullBytes, 0x02, 0xFA, 0x01, 0x00, 0x04)
+	fullBytes = append(fullBytes, "79161234567\x00\x00\x00\x00"...)
+
+	for _, tt := range []struct {
+		ti    SrTermIdentity
+		bytes []byte
+	}{
+		{imeiOnly, imeiOnlyBytes},
+		{full, fullBytes},
+	} {
+		tiBytes, err := tt.ti.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, tt.bytes, tiBytes)
+		}
+
+		decoded := SrTermIdentity{}
+		if assert.NoError(t, decoded.Decode(tt.bytes)) {
+			assert.Equal(t, tt.ti, decoded)
+		}
+	}
+
+	// значение длиннее поля не усекается
+	tooLong := imeiOnly
+	tooLong.IMEI = "3563070424410139"
+	_, err := tooLong.Encode()
+	assert.Error(t, err)
+
+	// поле, указанное флагами, передано не полностью
+	assert.Error(t, (&SrTermIdentity{}).Decode(imeiOnlyBytes[:len(imeiOnlyBytes)-1]))
+}
@@ -0,0 +1,66 @@
+package egtspb
+
+import (
+	"time"
+
+	"github.com/kuznetsovin/egts-protocol/libs/egts"
+)
+
+//FromSrPosData преобразует подзапись EGTS_SR_POS_DATA в protobuf сообщение Position
+func FromSrPosData(p *egts.SrPosData) *Position {
+	return &Position{
+		NavigationTime:      p.NavigationTime.Unix(),
+		Latitude:            p.Latitude,
+		Longitude:           p.Longitude,
+		Alte:                p.ALTE == "1",
+		Lohs:                p.LOHS == "1",
+		Lahs:                p.LAHS == "1",
+		Mv:                  p.MV == "1",
+		Bb:                  p.BB == "1",
+		Cs:                  p.CS == "1",
+		Fix:                 p.FIX == "1",
+		Vld:                 p.VLD == "1",
+		DirectionHighestBit: uint32(p.DirectionHighestBit),
+		AltitudeSign:        uint32(p.AltitudeSign),
+		Speed:               uint32(p.Speed),
+		Direction:           uint32(p.Direction),
+		Odometer:            p.Odometer,
+		DigitalInputs:       uint32(p.DigitalInputs),
+		Source:              uint32(p.Source),
+		Altitude:            p.Altitude,
+		SourceData:          int32(p.SourceData),
+	}
+}
+
+//ToSrPosData преобразует protobuf сообщение Position в подзапись EGTS_SR_POS_DATA
+func (m *Position) ToSrPosData() *egts.SrPosData {
+	return &egts.SrPosData{
+		NavigationTime:      time.Unix(m.GetNavigationTime(), 0).UTC(),
+		Latitude:            m.GetLatitude(),
+		Longitude:           m.GetLongitude(),
+		ALTE:                boolToBit(m.GetAlte()),
+		LOHS:                boolToBit(m.GetLohs()),
+		LAHS:                boolToBit(m.GetLahs()),
+		MV:                  boolToBit(m.GetMv()),
+		BB:                  boolToBit(m.GetBb()),
+		CS:                  boolToBit(m.GetCs()),
+		FIX:                 boolToBit(m.GetFix()),
+		VLD:                 boolToBit(m.GetVld()),
+		DirectionHighestBit: uint8(m.GetDirectionHighestBit()),
+		AltitudeSign:        uint8(m.GetAltitudeSign()),
+		Speed:               uint16(m.GetSpeed()),
+		Direction:           byte(m.GetDirection()),
+		Odometer:            m.GetOdometer(),
+		DigitalInputs:       byte(m.GetDigitalInputs()),
+		Source:              byte(m.GetSource()),
+		Altitude:            m.GetAltitude(),
+		SourceData:          int16(m.GetSourceData()),
+	}
+}
+
+func boolToBit(v bool) string {
+	if v {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,247 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: position.proto
+
+package egtspb
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Position struct {
+	NavigationTime       int64    `protobuf:"varint,1,opt,name=navigation_time,json=navigationTime,proto3" json:"navigation_time,omitempty"`
+	Latitude             float64  `protobuf:"fixed64,2,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude            float64  `protobuf:"fixed64,3,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Alte                 bool     `protobuf:"varint,4,opt,name=alte,proto3" json:"alte,omitempty"`
+	Lohs                 bool     `protobuf:"varint,5,opt,name=lohs,proto3" json:"lohs,omitempty"`
+	Lahs                 bool     `protobuf:"varint,6,opt,name=lahs,proto3" json:"lahs,omitempty"`
+	Mv                   bool     `protobuf:"varint,7,opt,name=mv,proto3" json:"mv,omitempty"`
+	Bb                   bool     `protobuf:"varint,8,opt,name=bb,proto3" json:"bb,omitempty"`
+	Cs                   bool     `protobuf:"varint,9,opt,name=cs,proto3" json:"cs,omitempty"`
+	Fix                  bool     `protobuf:"varint,10,opt,name=fix,proto3" json:"fix,omitempty"`
+	Vld                  bool     `protobuf:"varint,11,opt,name=vld,proto3" json:"vld,omitempty"`
+	DirectionHighestBit  uint32   `protobuf:"varint,12,opt,name=direction_highest_bit,json=directionHighestBit,proto3" json:"direction_highest_bit,omitempty"`
+	AltitudeSign         uint32   `protobuf:"varint,13,opt,name=altitude_sign,json=altitudeSign,proto3" json:"altitude_sign,omitempty"`
+	Speed                uint32   `protobuf:"varint,14,opt,name=speed,proto3" json:"speed,omitempty"`
+	Direction            uint32   `protobuf:"varint,15,opt,name=direction,proto3" json:"direction,omitempty"`
+	Odometer             []byte   `protobuf:"bytes,16,opt,name=odometer,proto3" json:"odometer,omitempty"`
+	DigitalInputs        uint32   `protobuf:"varint,17,opt,name=digital_inputs,json=digitalInputs,proto3" json:"digital_inputs,omitempty"`
+	Source               uint32   `protobuf:"varint,18,opt,name=source,proto3" json:"source,omitempty"`
+	Altitude             []byte   `protobuf:"bytes,19,opt,name=altitude,proto3" json:"altitude,omitempty"`
+	SourceData           int32    `protobuf:"varint,20,opt,name=source_data,json=sourceData,proto3" json:"source_data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Position) Reset()         { *m = Position{} }
+func (m *Position) String() string { return proto.CompactTextString(m) }
+func (*Position) ProtoMessage()    {}
+func (*Position) Descriptor() ([]byte, []int) {
+	return fileDescriptor_56e266f1a28a7893, []int{0}
+}
+
+func (m *Position) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Position.Unmarshal(m, b)
+}
+func (m *Position) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Position.Marshal(b, m, deterministic)
+}
+func (m *Position) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Position.Merge(m, src)
+}
+func (m *Position) XXX_Size() int {
+	return xxx_messageInfo_Position.Size(m)
+}
+func (m *Position) XXX_DiscardUnknown() {
+	xxx_messageInfo_Position.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Position proto.InternalMessageInfo
+
+func (m *Position) GetNavigationTime() int64 {
+	if m != nil {
+		return m.NavigationTime
+	}
+	return 0
+}
+
+func (m *Position) GetLatitude() float64 {
+	if m != nil {
+		return m.Latitude
+	}
+	return 0
+}
+
+func (m *Position) GetLongitude() float64 {
+	if m != nil {
+		return m.Longitude
+	}
+	return 0
+}
+
+func (m *Position) GetAlte() bool {
+	if m != nil {
+		return m.Alte
+	}
+	return false
+}
+
+func (m *Position) GetLohs() bool {
+	if m != nil {
+		return m.Lohs
+	}
+	return false
+}
+
+func (m *Position) GetLahs() bool {
+	if m != nil {
+		return m.Lahs
+	}
+	return false
+}
+
+func (m *Position) GetMv() bool {
+	if m != nil {
+		return m.Mv
+	}
+	return false
+}
+
+func (m *Position) GetBb() bool {
+	if m != nil {
+		return m.Bb
+	}
+	return false
+}
+
+func (m *Position) GetCs() bool {
+	if m != nil {
+		return m.Cs
+	}
+	return false
+}
+
+func (m *Position) GetFix() bool {
+	if m != nil {
+		return m.Fix
+	}
+	return false
+}
+
+func (m *Position) GetVld() bool {
+	if m != nil {
+		return m.Vld
+	}
+	return false
+}
+
+func (m *Position) GetDirectionHighestBit() uint32 {
+	if m != nil {
+		return m.DirectionHighestBit
+	}
+	return 0
+}
+
+func (m *Position) GetAltitudeSign() uint32 {
+	if m != nil {
+		return m.AltitudeSign
+	}
+	return 0
+}
+
+func (m *Position) GetSpeed() uint32 {
+	if m != nil {
+		return m.Speed
+	}
+	return 0
+}
+
+func (m *Position) GetDirection() uint32 {
+	if m != nil {
+		return m.Direction
+	}
+	return 0
+}
+
+func (m *Position) GetOdometer() []byte {
+	if m != nil {
+		return m.Odometer
+	}
+	return nil
+}
+
+func (m *Position) GetDigitalInputs() uint32 {
+	if m != nil {
+		return m.DigitalInputs
+	}
+	return 0
+}
+
+func (m *Position) GetSource() uint32 {
+	if m != nil {
+		return m.Source
+	}
+	return 0
+}
+
+func (m *Position) GetAltitude() []byte {
+	if m != nil {
+		return m.Altitude
+	}
+	return nil
+}
+
+func (m *Position) GetSourceData() int32 {
+	if m != nil {
+		return m.SourceData
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*Position)(nil), "egtspb.Position")
+}
+
+func init() {
+	proto.RegisterFile("position.proto", fileDescriptor_56e266f1a28a7893)
+}
+
+var fileDescriptor_56e266f1a28a7893 = []byte{
+	// 355 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x44, 0x92, 0xcf, 0xae, 0x95, 0x30,
+	0x10, 0x87, 0xd3, 0xf3, 0x07, 0xb9, 0x73, 0x0f, 0xdc, 0x6b, 0xef, 0xd1, 0x4c, 0x8c, 0x89, 0x44,
+	0x63, 0x64, 0xe5, 0x42, 0xdf, 0xc0, 0xb8, 0xd0, 0x9d, 0x41, 0xf7, 0xa4, 0xd0, 0x0a, 0x93, 0x14,
+	0x4a, 0x68, 0x0f, 0xf1, 0x79, 0x7c, 0xd2, 0x9b, 0xb6, 0x1c, 0xce, 0x6e, 0xbe, 0xef, 0x37, 0x4c,
+	0x98, 0x49, 0x21, 0x9f, 0x8c, 0x25, 0x47, 0x66, 0xfc, 0x3c, 0xcd, 0xc6, 0x19, 0x9e, 0xa8, 0xce,
+	0xd9, 0xa9, 0x79, 0xff, 0xff, 0x00, 0xe9, 0xaf, 0x35, 0xe2, 0x9f, 0xe0, 0x61, 0x14, 0x0b, 0x75,
+	0xc2, 0x53, 0xed, 0x68, 0x50, 0xc8, 0x0a, 0x56, 0xee, 0xab, 0xfc, 0xa6, 0xff, 0xd0, 0xa0, 0xf8,
+	0x1b, 0x48, 0xb5, 0x70, 0xe4, 0x2e, 0x52, 0xe1, 0xae, 0x60, 0x25, 0xab, 0x36, 0xe6, 0x6f, 0xe1,
+	0x4e, 0x9b, 0xb1, 0x8b, 0xe1, 0x3e, 0x84, 0x37, 0xc1, 0x39, 0x1c, 0x84, 0x76, 0x0a, 0x0f, 0x05,
+	0x2b, 0xd3, 0x2a, 0xd4, 0xde, 0x69, 0xd3, 0x5b, 0x3c, 0x46, 0xe7, 0xeb, 0xe0, 0x44, 0x6f, 0x31,
+	0x59, 0x9d, 0xe8, 0x2d, 0xcf, 0x61, 0x37, 0x2c, 0xf8, 0x22, 0x98, 0xdd, 0xb0, 0x78, 0x6e, 0x1a,
+	0x4c, 0x23, 0x37, 0x8d, 0xe7, 0xd6, 0xe2, 0x5d, 0xe4, 0xd6, 0xf2, 0x47, 0xd8, 0xff, 0xa5, 0x7f,
+	0x08, 0x41, 0xf8, 0xd2, 0x9b, 0x45, 0x4b, 0xbc, 0x8f, 0x66, 0xd1, 0x92, 0x7f, 0x81, 0x57, 0x92,
+	0x66, 0xd5, 0x86, 0x8d, 0x7b, 0xea, 0x7a, 0x65, 0x5d, 0xdd, 0x90, 0xc3, 0x53, 0xc1, 0xca, 0xac,
+	0x7a, 0xda, 0xc2, 0x1f, 0x31, 0xfb, 0x46, 0x8e, 0x7f, 0x80, 0x4c, 0xe8, 0xb8, 0x6d, 0x6d, 0xa9,
+	0x1b, 0x31, 0x0b, 0xbd, 0xa7, 0xab, 0xfc, 0x4d, 0xdd, 0xc8, 0xcf, 0x70, 0xb4, 0x93, 0x52, 0x12,
+	0xf3, 0x10, 0x46, 0xf0, 0xc7, 0xd9, 0x26, 0xe2, 0x43, 0x48, 0x6e, 0xc2, 0x9f, 0xd5, 0x48, 0x33,
+	0x28, 0xa7, 0x66, 0x7c, 0x2c, 0x58, 0x79, 0xaa, 0x36, 0xe6, 0x1f, 0x21, 0x97, 0xd4, 0x91, 0x13,
+	0xba, 0xa6, 0x71, 0xba, 0x38, 0x8b, 0x2f, 0xc3, 0xe7, 0xd9, 0x6a, 0x7f, 0x06, 0xc9, 0x5f, 0x43,
+	0x62, 0xcd, 0x65, 0x6e, 0x15, 0xf2, 0x10, 0xaf, 0xe4, 0x47, 0x5f, 0x7f, 0x0f, 0x9f, 0xe2, 0xe8,
+	0x2b, 0xf3, 0x77, 0x70, 0x1f, 0xbb, 0x6a, 0x29, 0x9c, 0xc0, 0x73, 0xc1, 0xca, 0x63, 0x05, 0x51,
+	0x7d, 0x17, 0x4e, 0x34, 0x49, 0x78, 0x33, 0x5f, 0x9f, 0x07, 0x00, 0x0e, 0x46, 0x0b, 0xf6, 0x45,
+	0x02, 0x00, 0x00,
+}
@@ -0,0 +1,46 @@
+package egtspb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/kuznetsovin/egts-protocol/libs/egts"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSrPosData = egts.SrPosData{
+	NavigationTime:      time.Date(2018, time.July, 6, 20, 8, 53, 0, time.UTC),
+	Latitude:            55.55389399769574,
+	Longitude:           37.43236696287812,
+	ALTE:                "1",
+	LOHS:                "0",
+	LAHS:                "0",
+	MV:                  "1",
+	BB:                  "0",
+	CS:                  "0",
+	FIX:                 "1",
+	VLD:                 "1",
+	DirectionHighestBit: 1,
+	AltitudeSign:        0,
+	Speed:               200,
+	Direction:           172,
+	Odometer:            []byte{0x01, 0x00, 0x00},
+	DigitalInputs:       0,
+	Source:              0,
+	Altitude:            []byte{0x10, 0x00, 0x00},
+}
+
+func TestPosition_RoundTrip(t *testing.T) {
+	msg := FromSrPosData(&testSrPosData)
+
+	data, err := proto.Marshal(msg)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	decoded := &Position{}
+	if assert.NoError(t, proto.Unmarshal(data, decoded)) {
+		assert.Equal(t, testSrPosData, *decoded.ToSrPosData())
+	}
+}
@@ -1,5 +1,79 @@
 package egts
 
+import (
+	"errors"
+	"fmt"
+)
+
+// причины ошибок разбора пакета, по которым вызывающий код может выбрать код результата обработки
+var (
+	//ErrShortBuffer данных меньше, чем указано в заголовке пакета
+	ErrShortBuffer = errors.New("недостаточно данных")
+	//ErrHeaderCRC контрольная сумма заголовка (HCS) не совпадает с вычисленной
+	ErrHeaderCRC = errors.New("не верная сумма заголовка")
+	//ErrDataCRC контрольная сумма тела пакета (SFRCS) не совпадает с вычисленной
+	ErrDataCRC = errors.New("не верная сумма тела пакета")
+)
+
+//ParseError ошибка разбора с указанием смещения в байтах, на котором она обнаружена. Смещение
+//отсчитывается от начала разбираемого фрагмента: при разборе пакета целиком - от начала пакета.
+//Err содержит причину ошибки (ErrShortBuffer, ErrHeaderCRC, ErrDataCRC), если она известна, Code - код
+//результата обработки для ответа EGTS_PT_RESPONSE (заполняется при разборе пакета)
+type ParseError struct {
+	Offset int
+	Msg    string
+	Err    error
+	Code   uint8
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s (смещение %d)", e.Msg, e.Offset)
+}
+
+//Unwrap возвращает причину ошибки для errors.Is
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+//ResultCode возвращает код результата обработки, соответствующий ошибке разбора, для поля PR ответа.
+//Для nil возвращается EGTS_PC_OK, для ошибки без известного кода - EGTS_PC_INC_DATAFORM
+func ResultCode(err error) byte {
+	if err == nil {
+		return egtsPcOk
+	}
+
+	if pe, ok := err.(*ParseError); ok && pe.Code != egtsPcOk {
+		return pe.Code
+	}
+
+	switch {
+	case errors.Is(err, ErrHeaderCRC):
+		return egtsPcHeaderCrcError
+	case errors.Is(err, ErrDataCRC):
+		return egtsPcDatacrcError
+	case errors.Is(err, ErrShortBuffer):
+		return egtsPcIncHeaderform
+	}
+	return egtsPcIncDataform
+}
+
+func newParseError(offset int, format string, a ...interface{}) error {
+	return &ParseError{Offset: offset, Msg: fmt.Sprintf(format, a...)}
+}
+
+func newShortBufferError(offset int, format string, a ...interface{}) error {
+	return &ParseError{Offset: offset, Msg: fmt.Sprintf(format, a...), Err: ErrShortBuffer}
+}
+
+// parseErrorAt переносит ошибку вложенного фрагмента, начинающегося со смещения offset, в систему
+// координат внешнего фрагмента
+func parseErrorAt(offset int, err error) error {
+	if pe, ok := err.(*ParseError); ok {
+		return &ParseError{Offset: offset + pe.Offset, Msg: pe.Msg, Err: pe.Err, Code: pe.Code}
+	}
+	return &ParseError{Offset: offset, Msg: err.Error()}
+}
+
 //код сообщения, что пакет успешно обработано
 const egtsPcOk = uint8(0)
 
@@ -116,3 +190,27 @@ const egtsPcModuleMemFlt = uint8(163)
 
 //тест не пройден
 const egtsPcTestFailed = uint8(164)
+
+// коды результата обработки для серверов, формирующих ответ вне пакета
+const (
+	//PcOk EGTS_PC_OK
+	PcOk = egtsPcOk
+	//PcInProgress EGTS_PC_IN_PROGRESS
+	PcInProgress = egtsPcInProgress
+	//PcUnsProtocol EGTS_PC_UNS_PROTOCOL
+	PcUnsProtocol = egtsPcUnsProtocol
+	//PcDecryptError EGTS_PC_DECRYPT_ERROR
+	PcDecryptError = egtsPcDecryptError
+	//PcIncHeaderform EGTS_PC_INC_HEADERFORM
+	PcIncHeaderform = egtsPcIncHeaderform
+	//PcIncDataform EGTS_PC_INC_DATAFORM
+	PcIncDataform = egtsPcIncDataform
+	//PcUnsType EGTS_PC_UNS_TYPE
+	PcUnsType = egtsPcUnsType
+	//PcHeaderCrcError EGTS_PC_HEADERCRC_ERROR
+	PcHeaderCrcError = egtsPcHeaderCrcError
+	//PcDatacrcError EGTS_PC_DATACRC_ERROR
+	PcDatacrcError = egtsPcDatacrcError
+	//PcAuthDenied EGTS_PC_AUTH_DENIED
+	PcAuthDenied = egtsPcAuthPenied
+)
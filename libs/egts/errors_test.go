@@ -0,0 +1,99 @@
+package egts
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func decodeParseError(t *testing.T, content []byte) *ParseError {
+	pkg := Package{}
+	_, err := pkg.Decode(content)
+	if !assert.Error(t, err) {
+		return nil
+	}
+
+	pe, ok := err.(*ParseError)
+	if !assert.True(t, ok, "ожидается ParseError, получено %T", err) {
+		return nil
+	}
+	return pe
+}
+
+func corruptPosDataPkg(offset int, val byte) []byte {
+	pkg := make([]byte, len(egtsPkgPosDataBytes))
+	copy(pkg, egtsPkgPosDataBytes)
+	pkg[offset] = val
+	return pkg
+}
+
+func TestParseError_Offset(t *testing.T) {
+	// заголовок обрывается перед FDL
+	if pe := decodeParseError(t, egtsPkgPosDataBytes[:5]); pe != nil {
+		assert.Equal(t, 5, pe.Offset)
+	}
+
+	// неверная контрольная сумма заголовка
+	if pe := decodeParseError(t, corruptPosDataPkg(10, 0x00)); pe != nil {
+		assert.Equal(t, 10, pe.Offset)
+	}
+
+	// неизвестный тип подзаписи: заголовок пакета 11 байт + заголовок записи с OID 11 байт
+	if pe := decodeParseError(t, corruptPosDataPkg(22, 0xFF)); pe != nil {
+		assert.Equal(t, 22, pe.Offset)
+	}
+
+	// неверная контрольная сумма тела пакета
+	if pe := decodeParseError(t, corruptPosDataPkg(len(egtsPkgPosDataBytes)-1, 0x00)); pe != nil {
+		assert.Equal(t, len(egtsPkgPosDataBytes)-2, pe.Offset)
+	}
+}
+
+func TestParseErrorAt(t *testing.T) {
+	err := parseErrorAt(11, parseErrorAt(3, newParseError(2, "ошибка")))
+	assert.Equal(t, &ParseError{Offset: 16, Msg: "ошибка"}, err)
+	assert.Equal(t, "ошибка (смещение 16)", err.Error())
+}
+
+func TestPackage_DecodeErrorKinds(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		code    uint8
+		err     error
+	}{
+		{"заголовок обрывается", egtsPkgPosDataBytes[:8], egtsPcIncHeaderform, ErrShortBuffer},
+		{"тело короче FDL", egtsPkgPosDataBytes[:20], egtsPcIncDataform, ErrShortBuffer},
+		{"SFRCS передана частично", egtsPkgPosDataBytes[:len(egtsPkgPosDataBytes)-1], egtsPcDatacrcError, ErrShortBuffer},
+		{"ошибка HCS", corruptPosDataPkg(10, 0x00), egtsPcHeaderCrcError, ErrHeaderCRC},
+		{"ошибка SFRCS", corruptPosDataPkg(len(egtsPkgPosDataBytes)-1, 0x00), egtsPcDatacrcError, ErrDataCRC},
+	}
+
+	for _, tt := range tests {
+		code, err := (&Package{Strict: true}).Decode(tt.content)
+		assert.Equal(t, tt.code, code, tt.name)
+		assert.True(t, errors.Is(err, tt.err), "%s: %v", tt.name, err)
+	}
+}
+
+func TestResultCode(t *testing.T) {
+	assert.Equal(t, PcOk, ResultCode(nil))
+
+	for _, tt := range []struct {
+		content []byte
+		code    byte
+	}{
+		{corruptPosDataPkg(10, 0x00), PcHeaderCrcError},
+		{corruptPosDataPkg(len(egtsPkgPosDataBytes)-1, 0x00), PcDatacrcError},
+		{egtsPkgPosDataBytes[:8], PcIncHeaderform},
+		{egtsPkgPosDataBytes[:len(egtsPkgPosDataBytes)-1], PcDatacrcError},
+		{corruptPosDataPkg(22, 0xFF), PcIncDataform},
+	} {
+		code, err := (&Package{}).Decode(tt.content)
+		assert.Equal(t, code, ResultCode(err))
+		assert.Equal(t, tt.code, ResultCode(err))
+	}
+
+	assert.Equal(t, PcDatacrcError, ResultCode(ErrDataCRC))
+	assert.Equal(t, PcIncDataform, ResultCode(errors.New("ошибка")))
+}
@@ -0,0 +1,66 @@
+package egts_test
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/kuznetsovin/egts-protocol/libs/egts"
+)
+
+func Example() {
+	// платформа: авторизует терминал и выводит принятые отметки
+	srv := &egts.Server{
+		RequireAuth: true,
+		Handler: func(rec *egts.ServiceDataRecord) (uint8, egts.Directive) {
+			for _, subRec := range rec.RecordDataSet {
+				switch data := subRec.SubrecordData.(type) {
+				case *egts.SrTermIdentity:
+					fmt.Printf("авторизация терминала %d\n", data.TerminalIdentifier)
+				case *egts.SrPosData:
+					fmt.Printf("отметка %s: %.4f, %.4f, %d км/ч\n",
+						data.NavigationTime.Format(time.RFC3339), data.Latitude, data.Longitude, data.Speed)
+				}
+			}
+			return egts.PcOk, egts.Continue
+		},
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer l.Close()
+	go func() { _ = srv.Serve(l) }()
+
+	// терминал: авторизуется и передает отметку
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer conn.Close()
+	client := egts.NewClient(conn)
+
+	identity := &egts.SrTermIdentity{TerminalIdentifier: 1024, MNE: "0", BSE: "0", NIDE: "0", SSRA: "1",
+		LNGCE: "0", IMSIE: "0", IMEIE: "0", HDIDE: "0"}
+	ntm := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	for _, pkg := range []*egts.Package{
+		egts.WrapSubrecord(egts.AuthService, identity, 1),
+		egts.NewTelematicsPackage(2, 1024, ntm, 55.7558, 37.6173, 60),
+	} {
+		acks, err := client.SendBatch([]*egts.Package{pkg})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("пакет %d подтвержден с кодом %d\n", pkg.PacketIdentifier, acks[pkg.PacketIdentifier].ProcessingResult)
+	}
+
+	// Output:
+	// авторизация терминала 1024
+	// пакет 1 подтвержден с кодом 0
+	// отметка 2021-03-01T10:00:00Z: 55.7558, 37.6173, 60 км/ч
+	// пакет 2 подтвержден с кодом 0
+}
@@ -0,0 +1,15 @@
+package egts
+
+import "math"
+
+//RoundFloat округляет значение до places знаков после запятой. Предназначена для вывода (JSON, CSV и т.д.),
+//значения в подзаписях не меняются, поэтому повторное кодирование остается точным. При places < 0 значение
+//возвращается без изменений
+func RoundFloat(v float64, places int) float64 {
+	if places < 0 {
+		return v
+	}
+
+	scale := math.Pow(10, float64(places))
+	return math.Round(v*scale) / scale
+}
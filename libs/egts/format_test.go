@@ -0,0 +1,26 @@
+package egts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRoundFloat(t *testing.T) {
+	assert.Equal(t, 55.7431, RoundFloat(55.74312345, 4))
+	assert.Equal(t, 56.0, RoundFloat(55.74312345, 0))
+	assert.Equal(t, 55.74312345, RoundFloat(55.74312345, -1))
+
+	// округление для вывода не затрагивает данные разобранной подзаписи
+	pos := SrPosData{}
+	if !assert.NoError(t, pos.Decode(testEgtsSrPosDataBytes)) {
+		return
+	}
+	point := lineProtocolPoint(1, &pos, 2)
+	assert.Contains(t, point, "lat=55.55,lon=37.43,")
+	assert.NotEqual(t, pos.Latitude, RoundFloat(pos.Latitude, 2))
+
+	posBytes, err := pos.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, testEgtsSrPosDataBytes, posBytes)
+	}
+}
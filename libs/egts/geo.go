@@ -0,0 +1,137 @@
+package egts
+
+import "math"
+
+// максимальные значения широты и долготы, которые кодируются значением 0xFFFFFFFF
+const (
+	maxLatitude  = 90.0
+	maxLongitude = 180.0
+)
+
+//EncodeLatitude кодирует широту в градусах в значение поля LAT (модуль широты, отнесенный к 90 и умноженный на
+//0xFFFFFFFF) с округлением до ближайшего целого и возвращает признак южного полушария (LAHS). Значения за
+//пределами ±90 ограничиваются полюсом, NaN кодируется как 0. Шаг кодирования 90/0xFFFFFFFF ≈ 2.1e-8 градуса
+//(около 2.3 мм), погрешность после обратного преобразования не превышает половины шага
+func EncodeLatitude(deg float64) (uint32, bool) {
+	return encodeCoordinate(deg, maxLatitude)
+}
+
+//DecodeLatitude преобразует значение поля LAT и признак южного полушария в широту в градусах со знаком
+func DecodeLatitude(raw uint32, south bool) float64 {
+	return decodeCoordinate(raw, south, maxLatitude)
+}
+
+//EncodeLongitude кодирует долготу в градусах в значение поля LONG (модуль долготы, отнесенный к 180 и
+//умноженный на 0xFFFFFFFF) с округлением до ближайшего целого и возвращает признак западного полушария (LOHS).
+//Значения за пределами ±180 ограничиваются антимеридианом, NaN кодируется как 0. Шаг кодирования
+//180/0xFFFFFFFF ≈ 4.2e-8 градуса (около 4.7 мм на экваторе)
+func EncodeLongitude(deg float64) (uint32, bool) {
+	return encodeCoordinate(deg, maxLongitude)
+}
+
+//DecodeLongitude преобразует значение поля LONG и признак западного полушария в долготу в градусах со знаком
+func DecodeLongitude(raw uint32, west bool) float64 {
+	return decodeCoordinate(raw, west, maxLongitude)
+}
+
+// encodeCoordinate кодирует модуль координаты относительно limit, на нуле полушарие не выставляется
+func encodeCoordinate(deg, limit float64) (uint32, bool) {
+	if math.IsNaN(deg) {
+		return 0, false
+	}
+
+	negative := deg < 0
+	abs := math.Min(math.Abs(deg), limit)
+	raw := uint32(math.Round(abs / limit * math.MaxUint32))
+	return raw, negative && raw != 0
+}
+
+// decodeCoordinate преобразует закодированный модуль координаты в градусы со знаком полушария
+func decodeCoordinate(raw uint32, negative bool, limit float64) float64 {
+	deg := float64(raw) * limit / math.MaxUint32
+	if negative {
+		deg = -deg
+	}
+	return deg
+}
+
+// signedCoordinates возвращает координаты точки в градусах с учетом полушарий (LAHS, LOHS)
+func signedCoordinates(p *SrPosData) (float64, float64) {
+	lat, lon := p.Latitude, p.Longitude
+	if p.LAHS == "1" {
+		lat = -lat
+	}
+	if p.LOHS == "1" {
+		lon = -lon
+	}
+	return lat, lon
+}
+
+//ComputeBearing вычисляет начальный азимут (в градусах от 0 до 360) по ортодромии между двумя
+//последовательными отметками. Используется вместо DIR, который ненадежен на малых скоростях
+func ComputeBearing(prev, cur *SrPosData) float64 {
+	lat1, lon1 := signedCoordinates(prev)
+	lat2, lon2 := signedCoordinates(cur)
+
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+
+	bearing := math.Atan2(y, x) * 180 / math.Pi
+	return math.Mod(bearing+360, 360)
+}
+
+// средний радиус Земли в метрах
+const earthRadiusMeters = 6371008.8
+
+//DistanceMeters вычисляет расстояние в метрах между двумя отметками по формуле гаверсинусов
+func DistanceMeters(prev, cur *SrPosData) float64 {
+	lat1, lon1 := signedCoordinates(prev)
+	lat2, lon2 := signedCoordinates(cur)
+
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+//TripAccumulator накапливает пройденное расстояние по последовательности отметок.
+//Отметки без достоверного навигационного решения пропускаются
+type TripAccumulator struct {
+	last   *SrPosData
+	meters float64
+}
+
+//Add учитывает очередную отметку
+func (t *TripAccumulator) Add(pos *SrPosData) {
+	if pos.FixType() == FixNone {
+		return
+	}
+
+	if t.last != nil {
+		t.meters += DistanceMeters(t.last, pos)
+	}
+	t.last = pos
+}
+
+//Meters возвращает пройденное расстояние в метрах
+func (t *TripAccumulator) Meters() float64 {
+	return t.meters
+}
+
+//AverageSpeed вычисляет среднюю скорость в км/ч между двумя отметками по расстоянию и разнице времени
+//навигации, не используя переданную терминалом мгновенную скорость. Если время не увеличилось,
+//возвращается false
+func AverageSpeed(prev, cur *SrPosData) (float64, bool) {
+	dt := cur.NavigationTime.Sub(prev.NavigationTime).Seconds()
+	if dt <= 0 {
+		return 0, false
+	}
+	return DistanceMeters(prev, cur) / dt * 3.6, true
+}
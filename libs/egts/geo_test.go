@@ -0,0 +1,109 @@
+package egts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeBearing(t *testing.T) {
+	// Лондон -> Париж
+	london := SrPosData{Latitude: 51.5074, Longitude: 0.1278, LAHS: "0", LOHS: "1"}
+	paris := SrPosData{Latitude: 48.8566, Longitude: 2.3522, LAHS: "0", LOHS: "0"}
+
+	assert.InDelta(t, 148.12, ComputeBearing(&london, &paris), 0.01)
+	assert.InDelta(t, 330.02, ComputeBearing(&paris, &london), 0.01)
+
+	origin := SrPosData{LAHS: "0", LOHS: "0"}
+	east := SrPosData{Longitude: 1, LAHS: "0", LOHS: "0"}
+	south := SrPosData{Latitude: 1, LAHS: "1", LOHS: "0"}
+
+	assert.InDelta(t, 90, ComputeBearing(&origin, &east), 1e-9)
+	assert.InDelta(t, 180, ComputeBearing(&origin, &south), 1e-9)
+}
+
+func TestDistanceMeters(t *testing.T) {
+	london := SrPosData{Latitude: 51.5074, Longitude: 0.1278, LAHS: "0", LOHS: "1"}
+	paris := SrPosData{Latitude: 48.8566, Longitude: 2.3522, LAHS: "0", LOHS: "0"}
+
+	assert.InDelta(t, 343560, DistanceMeters(&london, &paris), 1000)
+	assert.Equal(t, 0.0, DistanceMeters(&paris, &paris))
+}
+
+func TestTripAccumulator(t *testing.T) {
+	// три отрезка по 0.001 градуса долготы на экваторе, одна недостоверная отметка между ними
+	route := []SrPosData{
+		{Longitude: 0, VLD: "1"},
+		{Longitude: 0.001, VLD: "1"},
+		{Longitude: 5, VLD: "0"},
+		{Longitude: 0.002, VLD: "1"},
+		{Longitude: 0.003, VLD: "1"},
+	}
+
+	trip := TripAccumulator{}
+	for i := range route {
+		trip.Add(&route[i])
+	}
+
+	assert.InDelta(t, 333.6, trip.Meters(), 0.5)
+}
+
+func TestAverageSpeed(t *testing.T) {
+	start := testEgtsSrPosData.NavigationTime
+	// 0.01 градуса долготы на экваторе (~1112 м) за 60 секунд, терминал сообщает 20 км/ч
+	prev := SrPosData{NavigationTime: start, Speed: 20}
+	cur := SrPosData{NavigationTime: start.Add(time.Minute), Longitude: 0.01, Speed: 20}
+
+	speed, ok := AverageSpeed(&prev, &cur)
+	if assert.True(t, ok) {
+		assert.InDelta(t, 66.7, speed, 0.1)
+		assert.NotEqual(t, float64(cur.Speed), math.Round(speed))
+	}
+
+	_, ok = AverageSpeed(&cur, &prev)
+	assert.False(t, ok)
+}
+
+func TestCoordinateEncoding(t *testing.T) {
+	// половина шага кодирования широты и долготы
+	latTolerance := 90.0 / math.MaxUint32 / 2
+	lonTolerance := 180.0 / math.MaxUint32 / 2
+
+	for _, deg := range []float64{0, 90, -90, 55.75583, -33.86785, 1e-9, 89.99999999} {
+		raw, south := EncodeLatitude(deg)
+		assert.Equal(t, deg < 0, south, "%v", deg)
+		assert.InDelta(t, deg, DecodeLatitude(raw, south), latTolerance, "%v", deg)
+	}
+	for _, deg := range []float64{0, 180, -180, 37.61730, -122.41942, 179.9999999, -0.0000001} {
+		raw, west := EncodeLongitude(deg)
+		assert.Equal(t, deg < 0, west, "%v", deg)
+		assert.InDelta(t, deg, DecodeLongitude(raw, west), lonTolerance, "%v", deg)
+	}
+
+	// полюса и антимеридиан кодируются максимальным значением, выход за пределы ограничивается
+	raw, south := EncodeLatitude(-90)
+	assert.Equal(t, uint32(math.MaxUint32), raw)
+	assert.True(t, south)
+	raw, _ = EncodeLatitude(91)
+	assert.Equal(t, uint32(math.MaxUint32), raw)
+	raw, west := EncodeLongitude(-200)
+	assert.Equal(t, uint32(math.MaxUint32), raw)
+	assert.True(t, west)
+
+	// на нуле полушарие не выставляется
+	raw, south = EncodeLatitude(math.Copysign(0, -1))
+	assert.Equal(t, uint32(0), raw)
+	assert.False(t, south)
+	raw, south = EncodeLatitude(math.NaN())
+	assert.Equal(t, uint32(0), raw)
+	assert.False(t, south)
+
+	// разобранное значение кодируется обратно без потерь
+	for _, raw := range []uint32{1, 0x9E7A1C6F, 0x3CB535FF, math.MaxUint32 - 1} {
+		enc, _ := EncodeLatitude(DecodeLatitude(raw, false))
+		assert.Equal(t, raw, enc)
+		enc, _ = EncodeLongitude(DecodeLongitude(raw, true))
+		assert.Equal(t, raw, enc)
+	}
+}
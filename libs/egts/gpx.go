@@ -0,0 +1,76 @@
+package egts
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+type gpxDocument struct {
+	XMLName xml.Name `xml:"gpx"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Creator string   `xml:"creator,attr"`
+	Track   gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Segment gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat  string   `xml:"lat,attr"`
+	Lon  string   `xml:"lon,attr"`
+	Ele  *float64 `xml:"ele,omitempty"`
+	Time string   `xml:"time"`
+}
+
+//ExportGPX разбирает все отметки EGTS_SR_POS_DATA из потока пакетов captureReader и записывает их в out
+//в виде трека GPX 1.1 с временем навигации каждой точки
+func ExportGPX(captureReader io.Reader, out io.Writer) error {
+	doc := gpxDocument{
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+		Version: "1.1",
+		Creator: "egts-protocol",
+	}
+
+	positions, errs := StreamPositions(context.Background(), captureReader)
+	for pos := range positions {
+		lat, lon := signedCoordinates(pos)
+		point := gpxPoint{
+			Lat:  fmt.Sprintf("%.7f", lat),
+			Lon:  fmt.Sprintf("%.7f", lon),
+			Time: pos.NavigationTime.UTC().Format(time.RFC3339),
+		}
+
+		if pos.ALTE == "1" && len(pos.Altitude) == 3 {
+			ele := float64(uint32(pos.Altitude[0]) | uint32(pos.Altitude[1])<<8 | uint32(pos.Altitude[2])<<16)
+			if pos.AltitudeSign == 1 {
+				ele = -ele
+			}
+			point.Ele = &ele
+		}
+
+		doc.Track.Segment.Points = append(doc.Track.Segment.Points, point)
+	}
+	if err := <-errs; err != nil {
+		return fmt.Errorf("Не удалось разобрать поток пакетов: %v", err)
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return fmt.Errorf("Не удалось записать GPX: %v", err)
+	}
+
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("Не удалось записать GPX: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package egts
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExportGPX(t *testing.T) {
+	capture := bytes.Buffer{}
+	for i := 0; i < 3; i++ {
+		capture.Write(egtsPkgPosDataBytes)
+	}
+	capture.Write(goldenRoutedResponseBytes)
+
+	out := bytes.Buffer{}
+	if !assert.NoError(t, ExportGPX(&capture, &out)) {
+		return
+	}
+
+	doc := gpxDocument{}
+	if assert.NoError(t, xml.Unmarshal(out.Bytes(), &doc)) {
+		assert.Equal(t, "1.1", doc.Version)
+		if assert.Len(t, doc.Track.Segment.Points, 3) {
+			assert.Equal(t, "2018-07-05T20:08:53Z", doc.Track.Segment.Points[0].Time)
+		}
+	}
+}
+
+func TestExportGPX_Malformed(t *testing.T) {
+	assert.Error(t, ExportGPX(bytes.NewReader(egtsPkgPosDataBytes[:20]), &bytes.Buffer{}))
+}
@@ -0,0 +1,64 @@
+package egts
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+//LineProtocolMeasurement имя измерения для точек, формируемых ExportLineProtocol
+const LineProtocolMeasurement = "egts_position"
+
+//ExportLineProtocol разбирает все отметки EGTS_SR_POS_DATA из потока пакетов captureReader и записывает их в out
+//в формате InfluxDB line protocol: тег oid - идентификатор объекта записи, поля lat, lon, speed, время - NTM
+func ExportLineProtocol(captureReader io.Reader, out io.Writer) error {
+	return ExportLineProtocolPrecision(captureReader, out, -1)
+}
+
+//ExportLineProtocolPrecision то же, что ExportLineProtocol, но координаты округляются до places знаков после
+//запятой (см. RoundFloat). При places < 0 координаты записываются без округления
+func ExportLineProtocolPrecision(captureReader io.Reader, out io.Writer, places int) error {
+	for {
+		rawPkg, err := ReadPackage(captureReader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Не удалось прочитать пакет: %v", err)
+		}
+
+		pkg := Package{}
+		if _, err = pkg.Decode(rawPkg); err != nil {
+			return fmt.Errorf("Не удалось разобрать пакет: %v", err)
+		}
+
+		sds, ok := pkg.ServicesFrameData.(*ServiceDataSet)
+		if !ok {
+			continue
+		}
+
+		for _, rec := range *sds {
+			for _, subRec := range rec.RecordDataSet {
+				pos, ok := subRec.SubrecordData.(*SrPosData)
+				if !ok {
+					continue
+				}
+
+				if _, err = io.WriteString(out, lineProtocolPoint(rec.ObjectIdentifier, pos, places)); err != nil {
+					return fmt.Errorf("Не удалось записать точку: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// lineProtocolPoint формирует строку line protocol для одной отметки с точностью координат places
+func lineProtocolPoint(oid uint32, pos *SrPosData, places int) string {
+	lat, lon := signedCoordinates(pos)
+	lat, lon = RoundFloat(lat, places), RoundFloat(lon, places)
+	return LineProtocolMeasurement + ",oid=" + strconv.FormatUint(uint64(oid), 10) +
+		" lat=" + strconv.FormatFloat(lat, 'f', -1, 64) +
+		",lon=" + strconv.FormatFloat(lon, 'f', -1, 64) +
+		",speed=" + strconv.FormatUint(uint64(pos.Speed), 10) + "i" +
+		" " + strconv.FormatInt(pos.NavigationTime.UnixNano(), 10) + "\n"
+}
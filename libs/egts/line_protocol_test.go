@@ -0,0 +1,57 @@
+package egts
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportLineProtocol(t *testing.T) {
+	pos := testEgtsSrPosData
+	pos.NavigationTime = time.Date(2018, time.July, 5, 20, 8, 53, 0, time.UTC)
+	pos.Latitude = 55.5
+	pos.Longitude = 37.25
+	pos.LOHS = "1"
+	pos.Speed = 20
+	capture := bytes.Buffer{}
+	pkgBytes, err := NewAppdataPackage(1, ServiceDataSet{
+		NewServiceDataRecord(1, 1001, TeledataService, RecordDataSet{RecordData{SubrecordData: &pos}}),
+	}).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	capture.Write(pkgBytes)
+	capture.Write(goldenRoutedResponseBytes)
+	capture.Write(egtsPkgPosDataBytes)
+
+	out := bytes.Buffer{}
+	if !assert.NoError(t, ExportLineProtocol(&capture, &out)) {
+		return
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if assert.Len(t, lines, 2) {
+		var lat, lon float64
+		_, err = fmt.Sscanf(lines[0], "egts_position,oid=1001 lat=%g,lon=%g,speed=20i 1530821333000000000", &lat, &lon)
+		if assert.NoError(t, err) {
+			assert.InDelta(t, 55.5, lat, 1e-6)
+			assert.InDelta(t, -37.25, lon, 1e-6)
+		}
+		assert.Regexp(t, `^egts_position,oid=\d+ lat=[-\d.]+,lon=[-\d.]+,speed=\d+i \d+$`, lines[1])
+	}
+}
+
+func TestExportLineProtocolPrecision(t *testing.T) {
+	raw, rounded := bytes.Buffer{}, bytes.Buffer{}
+	if !assert.NoError(t, ExportLineProtocol(bytes.NewReader(egtsPkgPosDataBytes), &raw)) ||
+		!assert.NoError(t, ExportLineProtocolPrecision(bytes.NewReader(egtsPkgPosDataBytes), &rounded, 4)) {
+		return
+	}
+
+	assert.NotEqual(t, raw.String(), rounded.String())
+	assert.Equal(t, "egts_position,oid=133552 lat=55.5539,lon=37.4324,speed=200i 1530821333000000000\n",
+		rounded.String())
+}
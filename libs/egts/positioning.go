@@ -0,0 +1,82 @@
+package egts
+
+//PositioningStatus состояние определения местоположения по данным записи уровня поддержки услуг
+type PositioningStatus uint8
+
+const (
+	//PositioningNoFix местоположение определялось, но достоверного решения нет
+	PositioningNoFix PositioningStatus = iota
+	//PositioningValid в записи есть достоверные навигационные данные
+	PositioningValid
+	//PositioningNoCapability навигационный модуль отсутствует или выключен (флаг NMS в EGTS_SR_STATE_DATA),
+	//запись содержит только данные о состоянии. Это не ошибка определения местоположения
+	PositioningNoCapability
+)
+
+//NavigationModuleOn признак того, что навигационный модуль терминала включен (флаг NMS)
+func (e *SrStateData) NavigationModuleOn() bool {
+	return e.NMS == "1"
+}
+
+//PositioningStatus определяет состояние навигации по подзаписям: достоверная EGTS_SR_POS_DATA означает
+//PositioningValid, иначе при выключенном навигационном модуле - PositioningNoCapability
+func (r *ServiceDataRecord) PositioningStatus() PositioningStatus {
+	navModuleOff := false
+	for _, subRec := range r.RecordDataSet {
+		switch sr := subRec.SubrecordData.(type) {
+		case *SrPosData:
+			if sr.FixType() != FixNone {
+				return PositioningValid
+			}
+		case *SrStateData:
+			navModuleOff = !sr.NavigationModuleOn()
+		}
+	}
+
+	if navModuleOff {
+		return PositioningNoCapability
+	}
+	return PositioningNoFix
+}
+
+//AntennaFix навигационное решение одной антенны. Терминалы с двумя приемниками передают в записи по паре
+//EGTS_SR_POS_DATA и EGTS_SR_EXT_POS_DATA на каждую антенну, Antenna - порядковый номер пары начиная с 0
+type AntennaFix struct {
+	Antenna int
+	Pos     *SrPosData
+	ExtPos  *SrExtPosData
+}
+
+//Fixes возвращает навигационные решения записи с номерами антенн. N-я подзапись EGTS_SR_EXT_POS_DATA
+//относится к N-й EGTS_SR_POS_DATA, при отсутствии парной подзаписи соответствующее поле равно nil
+func (r *ServiceDataRecord) Fixes() []AntennaFix {
+	var (
+		positions    []*SrPosData
+		extPositions []*SrExtPosData
+	)
+	for _, subRec := range r.RecordDataSet {
+		switch sr := subRec.SubrecordData.(type) {
+		case *SrPosData:
+			positions = append(positions, sr)
+		case *SrExtPosData:
+			extPositions = append(extPositions, sr)
+		}
+	}
+
+	count := len(positions)
+	if len(extPositions) > count {
+		count = len(extPositions)
+	}
+
+	fixes := make([]AntennaFix, count)
+	for i := range fixes {
+		fixes[i].Antenna = i
+		if i < len(positions) {
+			fixes[i].Pos = positions[i]
+		}
+		if i < len(extPositions) {
+			fixes[i].ExtPos = extPositions[i]
+		}
+	}
+	return fixes
+}
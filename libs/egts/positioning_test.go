@@ -0,0 +1,68 @@
+package egts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestServiceDataRecord_PositioningStatusOnlyState(t *testing.T) {
+	state := testEgtsSrStateData
+	state.NMS = "0"
+
+	pkgBytes, err := WrapSubrecord(TeledataService, &state, 1).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	pkg := Package{}
+	if _, err = pkg.Decode(pkgBytes); assert.NoError(t, err) {
+		rec := (*pkg.ServicesFrameData.(*ServiceDataSet))[0]
+		assert.Equal(t, PositioningNoCapability, rec.PositioningStatus())
+	}
+}
+
+func TestServiceDataRecord_PositioningStatus(t *testing.T) {
+	validPos := testEgtsSrPosData
+	validPos.VLD = "1"
+	invalidPos := testEgtsSrPosData
+	invalidPos.VLD = "0"
+	stateOn := testEgtsSrStateData
+
+	rec := ServiceDataRecord{RecordDataSet: RecordDataSet{{SubrecordData: &invalidPos}, {SubrecordData: &stateOn}}}
+	assert.Equal(t, PositioningNoFix, rec.PositioningStatus())
+
+	rec = ServiceDataRecord{RecordDataSet: RecordDataSet{{SubrecordData: &validPos}}}
+	assert.Equal(t, PositioningValid, rec.PositioningStatus())
+}
+
+func TestServiceDataRecord_Fixes(t *testing.T) {
+	primaryPos, secondaryPos := testEgtsSrPosData, testEgtsSrPosData
+	secondaryPos.Latitude = 55.56
+	primaryExt, secondaryExt := testEgtsSrExtPosData, testEgtsSrExtPosData
+	secondaryExt.Satellites = 7
+
+	rec := NewServiceDataRecord(1, 1, TeledataService, RecordDataSet{
+		{SubrecordData: &primaryPos},
+		{SubrecordData: &primaryExt},
+		{SubrecordData: &secondaryPos},
+		{SubrecordData: &secondaryExt},
+	})
+	sdsBytes, err := (&ServiceDataSet{rec}).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sds := ServiceDataSet{}
+	if !assert.NoError(t, sds.Decode(sdsBytes)) {
+		return
+	}
+
+	fixes := sds[0].Fixes()
+	if assert.Len(t, fixes, 2) {
+		assert.Equal(t, 0, fixes[0].Antenna)
+		assert.Equal(t, &primaryExt, fixes[0].ExtPos)
+		assert.Equal(t, 1, fixes[1].Antenna)
+		assert.InDelta(t, 55.56, fixes[1].Pos.Latitude, 1e-6)
+		assert.Equal(t, uint8(7), fixes[1].ExtPos.Satellites)
+	}
+}
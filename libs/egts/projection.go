@@ -0,0 +1,42 @@
+package egts
+
+import "sync/atomic"
+
+//Projector преобразует географические координаты отметки (в градусах, со знаком полушария) в координаты
+//сетки, например для национальных систем координат
+type Projector interface {
+	Project(lat, lon float64) (x, y float64)
+}
+
+//NoopProjector проекция по умолчанию: преобразование не выполняется, координаты сетки остаются нулевыми
+type NoopProjector struct{}
+
+//Project возвращает нулевые координаты сетки
+func (NoopProjector) Project(lat, lon float64) (float64, float64) {
+	return 0, 0
+}
+
+// projector проекция, применяемая при разборе EGTS_SR_POS_DATA. Хранится в обертке registeredProjector,
+// так как atomic.Value допускает значения только одного конкретного типа
+var projector atomic.Value
+
+type registeredProjector struct {
+	Projector
+}
+
+//RegisterProjector задает проекцию, по которой при разборе EGTS_SR_POS_DATA заполняются GridX и GridY.
+//nil возвращает NoopProjector. Безопасна для вызова одновременно с разбором пакетов
+func RegisterProjector(p Projector) {
+	if p == nil {
+		p = NoopProjector{}
+	}
+	projector.Store(registeredProjector{p})
+}
+
+// currentProjector возвращает зарегистрированную проекцию или NoopProjector, если проекция не задана
+func currentProjector() Projector {
+	if p, ok := projector.Load().(registeredProjector); ok {
+		return p.Projector
+	}
+	return NoopProjector{}
+}
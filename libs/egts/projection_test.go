@@ -0,0 +1,58 @@
+package egts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+// stubProjector переносит координаты в сетку с масштабом 1000
+type stubProjector struct{}
+
+func (stubProjector) Project(lat, lon float64) (float64, float64) {
+	return lon * 1000, lat * 1000
+}
+
+func TestRegisterProjector(t *testing.T) {
+	pos := SrPosData{}
+	if assert.NoError(t, pos.Decode(testEgtsSrPosDataBytes)) {
+		assert.Equal(t, 0.0, pos.GridX)
+		assert.Equal(t, 0.0, pos.GridY)
+	}
+
+	RegisterProjector(stubProjector{})
+	defer RegisterProjector(nil)
+
+	pos = SrPosData{}
+	if assert.NoError(t, pos.Decode(testEgtsSrPosDataBytes)) {
+		assert.InDelta(t, 37432.367, pos.GridX, 1e-3)
+		assert.InDelta(t, 55553.894, pos.GridY, 1e-3)
+
+		// координаты сетки не попадают в подзапись
+		posBytes, err := pos.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, testEgtsSrPosDataBytes, posBytes)
+		}
+	}
+}
+
+func TestRegisterProjector_Concurrent(t *testing.T) {
+	defer RegisterProjector(nil)
+
+	// смена проекции во время разбора отметок в других горутинах (проверяется go test -race)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.NoError(t, (&SrPosData{}).Decode(testEgtsSrPosDataBytes))
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		RegisterProjector(stubProjector{})
+		RegisterProjector(nil)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,7 @@
+//go:build race
+// +build race
+
+package egts
+
+// raceEnabled признак сборки с детектором гонок, при котором выделение памяти не совпадает с обычной сборкой
+const raceEnabled = true
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"sort"
 )
 
 //RecordData структура секции подзапси у записи ServiceDataRecord
@@ -16,27 +17,79 @@ type RecordData struct {
 //RecordDataSet описывает массив с подзаписями протокола ЕГТС
 type RecordDataSet []RecordData
 
-//Decode разбирает байты в структуру подзаписи
-func (rds *RecordDataSet) Decode(recDS []byte) error {
-	var (
-		err error
-	)
-	buf := bytes.NewBuffer(recDS)
-	for buf.Len() > 0 {
-		rd := RecordData{}
-		if rd.SubrecordType, err = buf.ReadByte(); err != nil {
-			return fmt.Errorf("Не удалось получить тип записи subrecord data: %v", err)
+//WalkTLV перебирает поля вида тип (1 байт), длина (2 байта, little-endian), значение, из которых состоят
+//подзаписи ЕГТС, и передает каждое в onField. Позволяет обрабатывать подзаписи, которые библиотека еще не
+//поддерживает. Поле нулевой длины или выходящее за границы данных считается ошибкой, смещение ошибки из
+//onField отсчитывается от начала поля
+func WalkTLV(data []byte, onField func(tag uint8, value []byte) error) error {
+	buf := bytes.NewBuffer(data)
+	for index := 1; buf.Len() > 0; index++ {
+		fieldOffset := len(data) - buf.Len()
+		tag, err := buf.ReadByte()
+		if err != nil {
+			return newParseError(fieldOffset, "Не удалось получить тип записи subrecord data: %v", err)
 		}
 
 		tmpIntBuf := make([]byte, 2)
 		if _, err = buf.Read(tmpIntBuf); err != nil {
-			return fmt.Errorf("Не удалось получить длину записи subrecord data: %v", err)
+			return newParseError(fieldOffset+1, "Не удалось получить длину записи subrecord data: %v", err)
+		}
+		length := binary.LittleEndian.Uint16(tmpIntBuf)
+
+		// подзапись нулевой длины или выходящая за границы записи означает поврежденные данные
+		if length == 0 {
+			return newParseError(fieldOffset+1, "Нулевая длина подзаписи №%d типа %d", index, tag)
+		}
+		if int(length) > buf.Len() {
+			return newParseError(fieldOffset+1, "Длина подзаписи №%d типа %d: %d превышает оставшиеся данные записи: %d",
+				index, tag, length, buf.Len())
+		}
+
+		if err = onField(tag, buf.Next(int(length))); err != nil {
+			return parseErrorAt(fieldOffset, err)
+		}
+	}
+
+	return nil
+}
+
+// decodeMode режим разбора, который пакет передает записям и подзаписям: в строгом режиме отклонения от
+// спецификации приводят к ошибке, иначе корректируются с записью предупреждения в warnings. При keepUnknown
+// подзаписи неизвестных типов сохраняются как RawSubrecord
+type decodeMode struct {
+	strict      bool
+	keepUnknown bool
+	warnings    *[]string
+}
+
+func (m decodeMode) warn(format string, a ...interface{}) {
+	if m.warnings != nil {
+		*m.warnings = append(*m.warnings, fmt.Sprintf(format, a...))
+	}
+}
+
+//Decode разбирает байты в структуру подзаписи
+func (rds *RecordDataSet) Decode(recDS []byte) error {
+	_, err := rds.decode(recDS, decodeMode{})
+	return err
+}
+
+// decode разбирает подзаписи с учетом режима mode. Возвращает признак того, что длины подзаписей были
+// скорректированы и длину записи нужно пересчитать
+func (rds *RecordDataSet) decode(recDS []byte, mode decodeMode) (bool, error) {
+	corrected := false
+	err := WalkTLV(recDS, func(tag uint8, value []byte) error {
+		index := len(*rds) + 1
+		rd := RecordData{
+			SubrecordType:   tag,
+			SubrecordLength: uint16(len(value)),
 		}
-		rd.SubrecordLength = binary.LittleEndian.Uint16(tmpIntBuf)
 
-		subRecordBytes := buf.Next(int(rd.SubrecordLength))
+		if factory, ok := subrecordFactory(tag); ok {
+			return rds.decodeSubrecord(index, rd, factory(), value, mode, &corrected)
+		}
 
-		switch rd.SubrecordType {
+		switch tag {
 		case SrPosDataType:
 			rd.SubrecordData = &SrPosData{}
 		case SrTermIdentityType:
@@ -51,11 +104,13 @@ func (rds *RecordDataSet) Decode(recDS []byte) error {
 			rd.SubrecordData = &SrAdSensorsData{}
 		case SrType20:
 			// признак косвенный в спецификациях его нет
-			if rd.SubrecordLength == uint16(5) {
+			if len(value) == 5 {
 				rd.SubrecordData = &SrStateData{}
+			} else if mode.keepUnknown {
+				rd.SubrecordData = &RawSubrecord{SRT: tag}
 			} else {
 				// TODO: добавить секцию EGTS_SR_ACCEL_DATA
-				return fmt.Errorf("Не реализованная секция EGTS_SR_ACCEL_DATA: %d. Длина: %d. Содержимое: %X", rd.SubrecordType, rd.SubrecordLength, subRecordBytes)
+				return newParseError(0, "Не реализованная секция EGTS_SR_ACCEL_DATA: %d. Длина: %d. Содержимое: %X", rd.SubrecordType, len(value), value)
 			}
 		case SrStateDataType:
 			rd.SubrecordData = &SrStateData{}
@@ -71,19 +126,53 @@ func (rds *RecordDataSet) Decode(recDS []byte) error {
 			rd.SubrecordData = &StorageRecord{}
 		case SrAbsAnSensDataType:
 			rd.SubrecordData = &SrAbsAnSensData{}
+		case SrAbsDigSensDataType:
+			rd.SubrecordData = &SrAbsDigSensData{}
 		case SrDispatcherIdentityType:
 			rd.SubrecordData = &SrDispatcherIdentity{}
+		case SrCommandDataType:
+			rd.SubrecordData = &SrCommandData{}
 		default:
-			return fmt.Errorf("Не известный тип подзаписи: %d. Длина: %d. Содержимое: %X", rd.SubrecordType, rd.SubrecordLength, subRecordBytes)
+			if !mode.keepUnknown {
+				return newParseError(0, "Не известный тип подзаписи: %d. Длина: %d. Содержимое: %X", rd.SubrecordType, len(value), value)
+			}
+			rd.SubrecordData = &RawSubrecord{SRT: tag}
 		}
 
-		if err = rd.SubrecordData.Decode(subRecordBytes); err != nil {
-			return err
+		return rds.decodeSubrecord(index, rd, rd.SubrecordData, value, mode, &corrected)
+	})
+	return corrected, err
+}
+
+// decodeSubrecord разбирает данные подзаписи с порядковым номером index в srd и добавляет ее в набор. Данные
+// ограничены длиной SRL, поэтому ошибка в одной подзаписи не сдвигает разбор следующих
+func (rds *RecordDataSet) decodeSubrecord(index int, rd RecordData, srd BinaryData, value []byte, mode decodeMode, corrected *bool) error {
+	rd.SubrecordData = srd
+	if err := srd.Decode(value); err != nil {
+		err = parseErrorAt(3, err)
+		pe := err.(*ParseError)
+		pe.Msg = fmt.Sprintf("Подзапись №%d типа %d длиной %d: %s", index, rd.SubrecordType, len(value), pe.Msg)
+		return pe
+	}
+
+	// лишние байты подзаписи фиксированной длины при разборе не используются
+	fixedLen := fixedSubrecordLength(srd)
+	if fixedLen > 0 && len(value) > fixedLen {
+		if mode.strict {
+			return newParseError(1, "Длина подзаписи №%d типа %d: %d превышает установленную спецификацией: %d", index, rd.SubrecordType, len(value), fixedLen)
 		}
-		*rds = append(*rds, rd)
+		mode.warn("Подзапись №%d типа %d длиной %d усечена до %d байт", index, rd.SubrecordType, len(value), fixedLen)
+		rd.SubrecordLength = uint16(fixedLen)
+		*corrected = true
 	}
 
-	return err
+	// разобранные данные подзаписи переменной длины должны совпадать с SRL, иначе подзапись разобрана неверно
+	if decodedLen := int(srd.Length()); fixedLen == 0 && decodedLen > 0 && decodedLen != len(value) {
+		return newParseError(1, "Длина подзаписи №%d типа %d: %d не совпадает с разобранными данными: %d байт", index, rd.SubrecordType, len(value), decodedLen)
+	}
+
+	*rds = append(*rds, rd)
+	return nil
 }
 
 //Encode преобразовывает подзапись в набор байт
@@ -95,37 +184,8 @@ func (rds *RecordDataSet) Encode() ([]byte, error) {
 	buf := new(bytes.Buffer)
 
 	for _, rd := range *rds {
-		if rd.SubrecordType == 0 {
-			switch rd.SubrecordData.(type) {
-			case *SrPosData:
-				rd.SubrecordType = SrPosDataType
-			case *SrTermIdentity:
-				rd.SubrecordType = SrTermIdentityType
-			case *SrResponse:
-				rd.SubrecordType = SrRecordResponseType
-			case *SrResultCode:
-				rd.SubrecordType = SrResultCodeType
-			case *SrExtPosData:
-				rd.SubrecordType = SrExtPosDataType
-			case *SrAdSensorsData:
-				rd.SubrecordType = SrAdSensorsDataType
-			case *SrStateData:
-				rd.SubrecordType = SrStateDataType
-			case *SrLiquidLevelSensor:
-				rd.SubrecordType = SrLiquidLevelSensorType
-			case *SrAbsCntrData:
-				rd.SubrecordType = SrAbsCntrDataType
-			case *SrAuthInfo:
-				rd.SubrecordType = SrAuthInfoType
-			case *SrCountersData:
-				rd.SubrecordType = SrCountersDataType
-			case *StorageRecord:
-				rd.SubrecordType = SrEgtsPlusDataType
-			case *SrAbsAnSensData:
-				rd.SubrecordType = SrAbsAnSensDataType
-			default:
-				return result, fmt.Errorf("не известен код для данного типа подзаписи")
-			}
+		if rd.SubrecordType, err = rd.subrecordType(); err != nil {
+			return result, err
 		}
 
 		if err := binary.Write(buf, binary.LittleEndian, rd.SubrecordType); err != nil {
@@ -151,6 +211,103 @@ func (rds *RecordDataSet) Encode() ([]byte, error) {
 	return result, err
 }
 
+// subrecordType возвращает код типа подзаписи: явно заданный в SRT или определенный по типу данных SRD
+func (rd *RecordData) subrecordType() (byte, error) {
+	if rd.SubrecordType != 0 {
+		return rd.SubrecordType, nil
+	}
+
+	switch sr := rd.SubrecordData.(type) {
+	case *SrPosData:
+		return SrPosDataType, nil
+	case *SrTermIdentity:
+		return SrTermIdentityType, nil
+	case *SrResponse:
+		return SrRecordResponseType, nil
+	case *SrResultCode:
+		return SrResultCodeType, nil
+	case *SrExtPosData:
+		return SrExtPosDataType, nil
+	case *SrAdSensorsData:
+		return SrAdSensorsDataType, nil
+	case *SrStateData:
+		return SrStateDataType, nil
+	case *SrLiquidLevelSensor:
+		return SrLiquidLevelSensorType, nil
+	case *SrAbsCntrData:
+		return SrAbsCntrDataType, nil
+	case *SrAuthInfo:
+		return SrAuthInfoType, nil
+	case *SrCountersData:
+		return SrCountersDataType, nil
+	case *StorageRecord:
+		return SrEgtsPlusDataType, nil
+	case *SrAbsAnSensData:
+		return SrAbsAnSensDataType, nil
+	case *SrAbsDigSensData:
+		return SrAbsDigSensDataType, nil
+	case *SrDispatcherIdentity:
+		return SrDispatcherIdentityType, nil
+	case *SrCommandData:
+		return SrCommandDataType, nil
+	case Subrecord:
+		return sr.Type(), nil
+	default:
+		return 0, fmt.Errorf("не известен код для данного типа подзаписи")
+	}
+}
+
+// canonicalRank порядок следования подзаписей внутри записи: EGTS_SR_POS_DATA, затем EGTS_SR_EXT_POS_DATA,
+// затем остальные подзаписи по возрастанию кода типа
+func canonicalRank(srt byte) int {
+	switch srt {
+	case SrPosDataType:
+		return -2
+	case SrExtPosDataType:
+		return -1
+	}
+	return int(srt)
+}
+
+// fixedSubrecordLength длина подзаписи фиксированного размера или 0 для подзаписей переменной длины
+func fixedSubrecordLength(srd BinaryData) int {
+	switch srd.(type) {
+	case *SrStateData:
+		return 5
+	case *SrResponse:
+		return 3
+	case *SrResultCode:
+		return 1
+	}
+	return 0
+}
+
+//CanonicalOrder возвращает копию набора подзаписей, упорядоченную так, как того требуют платформы:
+//EGTS_SR_POS_DATA перед EGTS_SR_EXT_POS_DATA, остальные по возрастанию кода типа. Порядок подзаписей
+//одного типа сохраняется
+func (rds RecordDataSet) CanonicalOrder() (RecordDataSet, error) {
+	ranks := make([]int, len(rds))
+	for i := range rds {
+		srt, err := rds[i].subrecordType()
+		if err != nil {
+			return nil, err
+		}
+		ranks[i] = canonicalRank(srt)
+	}
+
+	idx := make([]int, len(rds))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return ranks[idx[i]] < ranks[idx[j]] })
+
+	sorted := make(RecordDataSet, len(rds))
+	for i, k := range idx {
+		sorted[i] = rds[k]
+	}
+	return sorted, nil
+}
+
 //Length получает длину массива записей
 func (rds *RecordDataSet) Length() uint16 {
 	var result uint16
@@ -1,6 +1,7 @@
 package egts
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -29,6 +30,7 @@ func TestRecordDataSet_Encode(t *testing.T) {
 				DirectionHighestBit: 1,
 				AltitudeSign:        0,
 				Speed:               200,
+				rawSpeed:            2000,
 				Direction:           172,
 				Odometer:            []byte{0x01, 0x00, 0x00},
 				DigitalInputs:       0,
@@ -64,6 +66,7 @@ func TestRecordDataSet_Decode(t *testing.T) {
 				DirectionHighestBit: 1,
 				AltitudeSign:        0,
 				Speed:               200,
+				rawSpeed:            2000,
 				Direction:           172,
 				Odometer:            []byte{0x01, 0x00, 0x00},
 				DigitalInputs:       0,
@@ -75,3 +78,194 @@ func TestRecordDataSet_Decode(t *testing.T) {
 		assert.Equal(t, rds, testRecordDataSet)
 	}
 }
+
+func TestRecordDataSet_DecodeZeroLength(t *testing.T) {
+	done := make(chan error, 1)
+	go func() {
+		rds := RecordDataSet{}
+		done <- rds.Decode([]byte{0x10, 0x00, 0x00, 0x10, 0x00, 0x00})
+	}()
+
+	select {
+	case err := <-done:
+		if assert.Error(t, err) {
+			assert.Equal(t, 1, err.(*ParseError).Offset)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("разбор подзаписи нулевой длины не завершился")
+	}
+
+	rds := RecordDataSet{}
+	assert.Error(t, rds.Decode([]byte{0x10, 0x15, 0x00, 0x01}))
+}
+
+func TestServiceDataRecord_CanonicalOrder(t *testing.T) {
+	pos := testEgtsSrPosData
+	extPos := testEgtsSrExtPosData
+	state := testEgtsSrStateData
+
+	rec := NewServiceDataRecord(1, 1, TeledataService, RecordDataSet{
+		{SubrecordData: &state},
+		{SubrecordData: &extPos},
+		{SubrecordData: &pos},
+	})
+	rec.CanonicalOrder = true
+
+	sdsBytes, err := (&ServiceDataSet{rec}).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sds := ServiceDataSet{}
+	if assert.NoError(t, sds.Decode(sdsBytes)) {
+		rds := sds[0].RecordDataSet
+		if assert.Len(t, rds, 3) {
+			assert.Equal(t, byte(SrPosDataType), rds[0].SubrecordType)
+			assert.Equal(t, byte(SrExtPosDataType), rds[1].SubrecordType)
+			assert.Equal(t, byte(SrStateDataType), rds[2].SubrecordType)
+		}
+	}
+
+	// исходный набор подзаписей не меняется
+	assert.Equal(t, &state, rec.RecordDataSet[0].SubrecordData)
+}
+
+func TestWalkTLV(t *testing.T) {
+	data := []byte{
+		0x10, 0x02, 0x00, 0xAA, 0xBB, // известный тип
+		0xEE, 0x01, 0x00, 0xCC, // тип, неизвестный библиотеке
+	}
+
+	type field struct {
+		tag   uint8
+		value []byte
+	}
+	fields := []field{}
+	err := WalkTLV(data, func(tag uint8, value []byte) error {
+		fields = append(fields, field{tag, value})
+		return nil
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []field{{0x10, []byte{0xAA, 0xBB}}, {0xEE, []byte{0xCC}}}, fields)
+	}
+
+	// ошибка обработчика получает смещение поля
+	err = WalkTLV(data, func(tag uint8, value []byte) error {
+		if tag == 0xEE {
+			return newParseError(3, "ошибка")
+		}
+		return nil
+	})
+	assert.Equal(t, &ParseError{Offset: 8, Msg: "ошибка"}, err)
+
+	assert.Error(t, WalkTLV([]byte{0x10, 0x05, 0x00, 0x01}, func(uint8, []byte) error { return nil }))
+}
+
+// rawFrame секция данных пакета, заданная байтами
+type rawFrame []byte
+
+func (f rawFrame) Decode([]byte) error     { return nil }
+func (f rawFrame) Encode() ([]byte, error) { return f, nil }
+func (f rawFrame) Length() uint16          { return uint16(len(f)) }
+
+func TestRecordDataSet_DecodeOverlongStateData(t *testing.T) {
+	// EGTS_SR_STATE_DATA длиной 7 байт вместо 5
+	rd := []byte{0x15, 0x07, 0x00, 0x02, 0x7F, 0x00, 0x00, 0x04, 0xAA, 0xBB}
+	sfrd := append([]byte{byte(len(rd)), 0x00, 0x01, 0x00, 0x00, TeledataService, TeledataService}, rd...)
+	pkgBytes, err := (&Package{ProtocolVersion: 1, Prefix: "00", Route: "0", EncryptionAlg: "00", Compression: "0",
+		PacketType: PtAppdataPacket, ServicesFrameData: rawFrame(sfrd)}).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	strict := Package{Strict: true}
+	_, err = strict.Decode(pkgBytes)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe)) {
+		// смещение указывает на SRL подзаписи
+		assert.Equal(t, DEFAULT_HEADER_LEN+7+1, pe.Offset)
+	}
+
+	lenient := Package{}
+	if _, err = lenient.Decode(pkgBytes); !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, lenient.Warnings, 1)
+
+	rec := (*lenient.ServicesFrameData.(*ServiceDataSet))[0]
+	assert.Equal(t, uint16(8), rec.RecordLength)
+	if assert.Len(t, rec.RecordDataSet, 1) {
+		assert.Equal(t, uint16(5), rec.RecordDataSet[0].SubrecordLength)
+		assert.Equal(t, &SrStateData{State: 2, MainPowerSourceVoltage: 0x7F, NMS: "1", IBU: "0", BBU: "0"},
+			rec.RecordDataSet[0].SubrecordData)
+	}
+
+	// после усечения пакет кодируется в корректную форму
+	fixed, err := lenient.Encode()
+	if assert.NoError(t, err) {
+		_, err = (&Package{Strict: true}).Decode(fixed)
+		assert.NoError(t, err)
+	}
+}
+
+func TestRecordDataSet_DecodePosDataZeroSourceData(t *testing.T) {
+	// POS_DATA длиной 23 байта: SRCD передан и равен 0
+	rdBytes := append([]byte{0x10, 0x17, 0x00}, testRecordDataBytes[3:]...)
+	rdBytes = append(rdBytes, 0x00, 0x00)
+
+	rds := RecordDataSet{}
+	corrected, err := rds.decode(rdBytes, decodeMode{strict: true})
+	if assert.NoError(t, err) && assert.Len(t, rds, 1) {
+		assert.False(t, corrected)
+		pos := rds[0].SubrecordData.(*SrPosData)
+		assert.True(t, pos.SourceDataExists)
+		assert.Equal(t, int16(0), pos.SourceData)
+		assert.Equal(t, uint16(23), pos.Length())
+
+		encoded, err := rds.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, rdBytes, encoded)
+		}
+	}
+}
+
+func TestRecordDataSet_DecodeSubrecordLengthMismatch(t *testing.T) {
+	posData := testRecordDataBytes[3:]
+
+	// SRL больше данных подзаписи: лишний байт в конце
+	tooLarge := append(append([]byte{}, testRecordDataBytes...), 0x10, 0x16, 0x00)
+	tooLarge = append(append(tooLarge, posData...), 0xAA)
+
+	_, err := (&RecordDataSet{}).decode(tooLarge, decodeMode{strict: true})
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe)) {
+		assert.Equal(t, len(testRecordDataBytes)+1, pe.Offset)
+		assert.Contains(t, pe.Msg, "подзаписи №2 типа 16")
+	}
+
+	// в нестрогом режиме несовпадение длины также считается ошибкой
+	var warnings []string
+	_, err = (&RecordDataSet{}).decode(tooLarge, decodeMode{warnings: &warnings})
+	if assert.True(t, errors.As(err, &pe)) {
+		assert.Equal(t, len(testRecordDataBytes)+1, pe.Offset)
+		assert.Contains(t, pe.Msg, "не совпадает с разобранными данными")
+		assert.Empty(t, warnings)
+	}
+
+	// SRL меньше данных подзаписи: следующая подзапись не разбирается со сдвигом
+	tooSmall := append(append([]byte{}, testRecordDataBytes...), 0x10, 0x14, 0x00)
+	tooSmall = append(tooSmall, posData[:20]...)
+	tooSmall = append(tooSmall, testRecordDataBytes...)
+
+	err = (&RecordDataSet{}).Decode(tooSmall)
+	if assert.True(t, errors.As(err, &pe)) {
+		assert.Contains(t, pe.Msg, "Подзапись №2 типа 16 длиной 20")
+	}
+
+	// SRL выходит за границу записи
+	err = (&RecordDataSet{}).Decode(append(append([]byte{}, testRecordDataBytes...), 0x10, 0x15, 0x00, 0x01))
+	if assert.True(t, errors.As(err, &pe)) {
+		assert.Equal(t, len(testRecordDataBytes)+1, pe.Offset)
+		assert.Contains(t, pe.Msg, "подзаписи №2 типа 16")
+	}
+}
@@ -0,0 +1,324 @@
+package egts
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+)
+
+//Directive указание серверу, как поступить с соединением после отправки ответа
+type Directive uint8
+
+const (
+	//Continue продолжить прием пакетов
+	Continue Directive = iota
+	//CloseConnection закрыть соединение после отправки ответа (например, при неудачной авторизации)
+	CloseConnection
+)
+
+//RecordHandler обработчик записи уровня поддержки услуг. Возвращает код результата обработки, который
+//передается терминалу в поле RST подзаписи EGTS_SR_RECORD_RESPONSE, и указание серверу
+type RecordHandler func(rec *ServiceDataRecord) (uint8, Directive)
+
+//ContextRecordHandler обработчик записи, который получает контекст с крайним сроком обработки
+//Server.HandlerTimeout. Контекст отменяется, когда срок истек и запись подтверждена без результата обработчика
+type ContextRecordHandler func(ctx context.Context, rec *ServiceDataRecord) (uint8, Directive)
+
+//StoreHandler обработчик для платформ, которые подтверждают запись только после ее надежного сохранения
+//(семантика at-least-once). store вызывается синхронно, ошибка сохранения подтверждается кодом failureResult
+//(по умолчанию EGTS_PC_NO_RES_AVAIL), чтобы терминал повторил отправку записи
+func StoreHandler(store func(rec *ServiceDataRecord) error, failureResult uint8) RecordHandler {
+	if failureResult == egtsPcOk {
+		failureResult = egtsPcNoResAvail
+	}
+
+	return func(rec *ServiceDataRecord) (uint8, Directive) {
+		if err := store(rec); err != nil {
+			return failureResult, Continue
+		}
+		return egtsPcOk, Continue
+	}
+}
+
+//Server сервер, принимающий пакеты ЕГТС и подтверждающий каждую полученную запись
+type Server struct {
+	Handler RecordHandler
+	// RequireAuth не передавать обработчику записи сервисов, отличных от EGTS_AUTH_SERVICE, пока терминал
+	// не прошел авторизацию. Такие записи подтверждаются с кодом EGTS_PC_AUTH_DENIED
+	RequireAuth bool
+	// ContextHandler обработчик записи с контекстом, учитывающим HandlerTimeout. Если задан, используется
+	// вместо Handler
+	ContextHandler ContextRecordHandler
+	// HandlerTimeout максимальное время обработки одной записи. Если обработчик не уложился, запись
+	// подтверждается с кодом TimeoutResult, контекст ContextHandler отменяется, а результат обработчика
+	// отбрасывается: итоговый результат по такой записи сервер терминалу не передает, поэтому обработчик,
+	// завершивший работу после отмены, должен сам сообщить его платформе (например, командой терминалу).
+	// 0 - без ограничения, тогда ответ формируется только после того, как обработчик вернул результат по
+	// каждой записи
+	HandlerTimeout time.Duration
+	// TimeoutResult код подтверждения записи по истечении HandlerTimeout, по умолчанию EGTS_PC_IN_PROGRESS
+	TimeoutResult uint8
+	// Strict и KeepUnknownSubrecords режимы разбора принятых пакетов (см. Package)
+	Strict                bool
+	KeepUnknownSubrecords bool
+	// DispatcherIdentity учетные данные платформы, которые передаются терминалу подзаписью
+	// EGTS_SR_DISPATCHER_IDENTITY в подтверждении успешной авторизации и в пакете с ее результатом. nil - не
+	// передавать
+	DispatcherIdentity *SrDispatcherIdentity
+
+	pid Uint16Counter
+	rn  Uint16Counter
+}
+
+//Serve принимает соединения на l и обрабатывает каждое в отдельной горутине
+func (s *Server) Serve(l net.Listener) error {
+	return s.serve(l, s.recordHandler())
+}
+
+func (s *Server) serve(l net.Listener, handler ContextRecordHandler) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn, handler)
+	}
+}
+
+//ListenAndServe принимает TCP соединения на адресе addr и передает записи принятых пакетов обработчику handler.
+//Если handler равен nil, используется обработчик сервера. Поля сервера не меняются, поэтому один Server
+//может обслуживать несколько адресов с разными обработчиками
+func (s *Server) ListenAndServe(addr string, handler RecordHandler) error {
+	recordHandler := s.recordHandler()
+	if handler != nil {
+		recordHandler = func(_ context.Context, rec *ServiceDataRecord) (uint8, Directive) {
+			return handler(rec)
+		}
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("Не удалось открыть порт %s: %v", addr, err)
+	}
+	defer l.Close()
+
+	return s.serve(l, recordHandler)
+}
+
+//ListenAndServeTLS принимает TLS соединения на адресе addr, используя сертификат certFile и ключ keyFile
+func (s *Server) ListenAndServeTLS(addr, certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("Не удалось загрузить сертификат TLS: %v", err)
+	}
+
+	l, err := tls.Listen("tcp", addr, &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		return fmt.Errorf("Не удалось открыть порт %s: %v", addr, err)
+	}
+	defer l.Close()
+
+	return s.Serve(l)
+}
+
+// connState состояние соединения: терминал сначала не авторизован, после успешной обработки
+// EGTS_SR_TERM_IDENTITY считается авторизованным
+type connState uint8
+
+const (
+	stateUnauthenticated connState = iota
+	stateAuthenticated
+)
+
+//ServeConn обрабатывает пакеты одного соединения до его закрытия клиентом или по указанию обработчика.
+//Подходит любое net.Conn, в том числе *tls.Conn
+func (s *Server) ServeConn(conn net.Conn) {
+	s.serveConn(conn, s.recordHandler())
+}
+
+func (s *Server) serveConn(conn net.Conn, handler ContextRecordHandler) {
+	defer conn.Close()
+
+	dec := NewDecoder(conn)
+	dec.Strict, dec.KeepUnknownSubrecords = s.Strict, s.KeepUnknownSubrecords
+
+	state := stateUnauthenticated
+	for {
+		pkg, err := dec.Decode()
+		if pkg == nil {
+			return
+		}
+
+		responses, directive := s.handlePackage(pkg, err, handler, &state)
+		for _, resp := range responses {
+			respBytes, err := resp.Encode()
+			if err != nil {
+				return
+			}
+
+			if _, err = conn.Write(respBytes); err != nil {
+				return
+			}
+		}
+
+		if directive == CloseConnection {
+			return
+		}
+	}
+}
+
+// handlePackage передает записи разобранного пакета EGTS_PT_APPDATA или EGTS_PT_SIGNED_APPDATA обработчику и
+// формирует ответ EGTS_PT_RESPONSE, при ошибке разбора decodeErr - с ее кодом результата. После успешной
+// авторизации за ответом следует пакет с результатом авторизации EGTS_SR_RESULT_CODE
+func (s *Server) handlePackage(pkg *Package, decodeErr error, handler ContextRecordHandler, state *connState) ([]*Package, Directive) {
+	err := decodeErr
+	if err == nil {
+		err = pkg.Validate()
+	}
+	if err != nil {
+		return []*Package{s.newResponse(pkg.PacketIdentifier, ResultCode(err), nil)}, Continue
+	}
+
+	// записи подписанного пакета обрабатываются так же, как записи EGTS_PT_APPDATA, проверка подписи SIGD
+	// остается за платформой
+	if pkg.PacketType != PtAppdataPacket && pkg.PacketType != PtSignedAppdataPacket {
+		return nil, Continue
+	}
+
+	// пакет только из заголовка (FDL = 0) допустим и подтверждается без записей
+	sds, ok := pkg.ServicesFrameData.(*ServiceDataSet)
+	if !ok || sds == nil {
+		return []*Package{s.newResponse(pkg.PacketIdentifier, egtsPcOk, nil)}, Continue
+	}
+
+	directive := Continue
+	authenticated := false
+	records := ServiceDataSet{}
+	for _, rec := range *sds {
+		if s.RequireAuth && *state != stateAuthenticated && rec.SourceServiceType != AuthService {
+			records = append(records, s.newRecordResponse(rec, egtsPcAuthPenied))
+			continue
+		}
+
+		recordStatus := egtsPcOk
+		if handler != nil {
+			var recDirective Directive
+			recordStatus, recDirective = s.callHandler(handler, rec)
+			if recDirective == CloseConnection {
+				directive = CloseConnection
+			}
+		}
+
+		if recordStatus == egtsPcOk && rec.SourceServiceType == AuthService && hasTermIdentity(rec) {
+			*state = stateAuthenticated
+			authenticated = true
+			records = append(records, s.newAuthResponse(rec))
+			continue
+		}
+
+		records = append(records, s.newRecordResponse(rec, recordStatus))
+	}
+
+	responses := []*Package{s.newResponse(pkg.PacketIdentifier, egtsPcOk, records)}
+	if authenticated {
+		authResult, err := BuildAuthResponse(s.pid.Next(), egtsPcOk, s.DispatcherIdentity)
+		if err != nil {
+			return responses, directive
+		}
+		responses = append(responses, authResult)
+	}
+	return responses, directive
+}
+
+// recordHandler возвращает обработчик записей сервера или nil, если обработчик не задан
+func (s *Server) recordHandler() ContextRecordHandler {
+	if s.ContextHandler != nil {
+		return s.ContextHandler
+	}
+	if s.Handler == nil {
+		return nil
+	}
+	return func(_ context.Context, rec *ServiceDataRecord) (uint8, Directive) {
+		return s.Handler(rec)
+	}
+}
+
+// callHandler вызывает обработчик записи с учетом HandlerTimeout
+func (s *Server) callHandler(handler ContextRecordHandler, rec ServiceDataRecord) (uint8, Directive) {
+	if s.HandlerTimeout <= 0 {
+		return handler(context.Background(), &rec)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.HandlerTimeout)
+	defer cancel()
+
+	type handlerResult struct {
+		status    uint8
+		directive Directive
+	}
+	done := make(chan handlerResult, 1)
+	go func() {
+		status, directive := handler(ctx, &rec)
+		done <- handlerResult{status, directive}
+	}()
+
+	select {
+	case res := <-done:
+		return res.status, res.directive
+	case <-ctx.Done():
+		if s.TimeoutResult == egtsPcOk {
+			return egtsPcInProgress, Continue
+		}
+		return s.TimeoutResult, Continue
+	}
+}
+
+// hasTermIdentity признак наличия в записи учетных данных терминала
+func hasTermIdentity(rec ServiceDataRecord) bool {
+	for _, subRec := range rec.RecordDataSet {
+		if _, ok := subRec.SubrecordData.(*SrTermIdentity); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newRecordResponse формирует запись с подтверждением EGTS_SR_RECORD_RESPONSE для записи rec, за которым
+// следуют подзаписи extra
+func (s *Server) newRecordResponse(rec ServiceDataRecord, recordStatus uint8, extra ...RecordData) ServiceDataRecord {
+	rds := RecordDataSet{
+		RecordData{
+			SubrecordType: SrRecordResponseType,
+			SubrecordData: &SrResponse{
+				ConfirmedRecordNumber: rec.RecordNumber,
+				RecordStatus:          recordStatus,
+			},
+		},
+	}
+	rds = append(rds, extra...)
+
+	resp := NewServiceDataRecord(s.rn.Next(), 0, rec.SourceServiceType, rds)
+	resp.SourceServiceOnDevice = "0"
+	resp.ObjectIDFieldExists = "0"
+	return resp
+}
+
+// newAuthResponse формирует подтверждение успешной авторизации, дополняя его учетными данными платформы,
+// если они заданы
+func (s *Server) newAuthResponse(rec ServiceDataRecord) ServiceDataRecord {
+	if s.DispatcherIdentity == nil {
+		return s.newRecordResponse(rec, egtsPcOk)
+	}
+
+	return s.newRecordResponse(rec, egtsPcOk, RecordData{
+		SubrecordType: SrDispatcherIdentityType,
+		SubrecordData: s.DispatcherIdentity,
+	})
+}
+
+// newResponse формирует пакет EGTS_PT_RESPONSE на пакет с идентификатором rpid
+func (s *Server) newResponse(rpid uint16, processingResult uint8, records ServiceDataSet) *Package {
+	return NewResponsePackage(s.pid.Next(), rpid, processingResult, records)
+}
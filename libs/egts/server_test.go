@@ -0,0 +1,443 @@
+package egts
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// запускает сервер на одном конце соединения в памяти и возвращает клиентский конец
+func startTestServer(srv *Server) net.Conn {
+	serverConn, clientConn := net.Pipe()
+	go srv.ServeConn(serverConn)
+
+	_ = clientConn.SetDeadline(time.Now().Add(2 * time.Second))
+	return clientConn
+}
+
+func readTestResponse(t *testing.T, conn net.Conn) *PtResponse {
+	rawResp, err := ReadPackage(conn)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+
+	resp := Package{}
+	if _, err = resp.Decode(rawResp); !assert.NoError(t, err) {
+		return nil
+	}
+	return resp.ServicesFrameData.(*PtResponse)
+}
+
+// readTestAuthResult читает пакет с результатом авторизации и возвращает код EGTS_SR_RESULT_CODE
+func readTestAuthResult(t *testing.T, conn net.Conn) uint8 {
+	rawPkg, err := ReadPackage(conn)
+	if !assert.NoError(t, err) {
+		return 0xFF
+	}
+
+	pkg := Package{}
+	if _, err = pkg.Decode(rawPkg); !assert.NoError(t, err) {
+		return 0xFF
+	}
+	rec := (*pkg.ServicesFrameData.(*ServiceDataSet))[0]
+	assert.Equal(t, byte(AuthService), rec.SourceServiceType)
+	return rec.RecordDataSet[0].SubrecordData.(*SrResultCode).ResultCode
+}
+
+func TestServer_Confirm(t *testing.T) {
+	conn := startTestServer(&Server{})
+	defer conn.Close()
+
+	_, _ = conn.Write(egtsPkgPosDataBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		assert.Equal(t, uint16(138), resp.ResponsePacketID)
+		assert.Equal(t, egtsPcOk, resp.ProcessingResult)
+
+		rec := (*resp.SDR.(*ServiceDataSet))[0]
+		assert.Equal(t, byte(TeledataService), rec.SourceServiceType)
+		assert.Equal(t, &SrResponse{ConfirmedRecordNumber: 97, RecordStatus: egtsPcOk}, rec.RecordDataSet[0].SubrecordData)
+	}
+}
+
+func TestServer_HeaderOnlyPackage(t *testing.T) {
+	handled := 0
+	conn := startTestServer(&Server{
+		Handler: func(rec *ServiceDataRecord) (uint8, Directive) {
+			handled++
+			return egtsPcOk, Continue
+		},
+	})
+	defer conn.Close()
+
+	// EGTS_PT_APPDATA с FDL = 0
+	headerOnly := []byte{0x01, 0x00, 0x00, 0x0B, 0x00, 0x00, 0x00, 0x05, 0x00, 0x01, 0x4A}
+	_, _ = conn.Write(headerOnly)
+	if resp := readTestResponse(t, conn); resp != nil {
+		assert.Equal(t, uint16(5), resp.ResponsePacketID)
+		assert.Equal(t, egtsPcOk, resp.ProcessingResult)
+		assert.Nil(t, resp.SDR)
+	}
+
+	// соединение продолжает обрабатывать пакеты
+	_, _ = conn.Write(egtsPkgPosDataBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		assert.Equal(t, uint16(138), resp.ResponsePacketID)
+	}
+	assert.Equal(t, 1, handled)
+}
+
+func TestServer_ConfirmSignedAppdata(t *testing.T) {
+	handled := 0
+	conn := startTestServer(&Server{
+		Handler: func(rec *ServiceDataRecord) (uint8, Directive) {
+			handled++
+			return egtsPcOk, Continue
+		},
+	})
+	defer conn.Close()
+
+	pos := testEgtsSrPosData
+	pkg := WrapSubrecord(TeledataService, &pos, 7)
+	pkg.PacketType = PtSignedAppdataPacket
+	pkg.Signature = []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, _ = conn.Write(pkgBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		assert.Equal(t, uint16(7), resp.ResponsePacketID)
+		assert.Equal(t, egtsPcOk, resp.ProcessingResult)
+
+		rec := (*resp.SDR.(*ServiceDataSet))[0]
+		assert.Equal(t, egtsPcOk, rec.RecordDataSet[0].SubrecordData.(*SrResponse).RecordStatus)
+	}
+	assert.Equal(t, 1, handled)
+}
+
+func TestServer_HandlerCloseDirective(t *testing.T) {
+	conn := startTestServer(&Server{
+		Handler: func(rec *ServiceDataRecord) (uint8, Directive) {
+			return egtsPcAuthPenied, CloseConnection
+		},
+	})
+	defer conn.Close()
+
+	_, _ = conn.Write(egtsPkgPosDataBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		rec := (*resp.SDR.(*ServiceDataSet))[0]
+		assert.Equal(t, egtsPcAuthPenied, rec.RecordDataSet[0].SubrecordData.(*SrResponse).RecordStatus)
+	}
+
+	// после ответа сервер закрывает соединение
+	_, err := conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestServer_RequireAuth(t *testing.T) {
+	handled := 0
+	conn := startTestServer(&Server{
+		RequireAuth: true,
+		Handler: func(rec *ServiceDataRecord) (uint8, Directive) {
+			handled++
+			return egtsPcOk, Continue
+		},
+	})
+	defer conn.Close()
+
+	// данные до авторизации отклоняются без вызова обработчика
+	_, _ = conn.Write(egtsPkgPosDataBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		rec := (*resp.SDR.(*ServiceDataSet))[0]
+		assert.Equal(t, egtsPcAuthPenied, rec.RecordDataSet[0].SubrecordData.(*SrResponse).RecordStatus)
+	}
+	assert.Equal(t, 0, handled)
+
+	identity := testEgtsSrTermIdentity
+	pos := testEgtsSrPosData
+	pkgBytes, err := NewIdentityWithPositionPackage(2, 1, &identity, &pos).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// после TERM_IDENTITY данные того же и последующих пакетов принимаются
+	_, _ = conn.Write(pkgBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		for _, rec := range *resp.SDR.(*ServiceDataSet) {
+			assert.Equal(t, egtsPcOk, rec.RecordDataSet[0].SubrecordData.(*SrResponse).RecordStatus)
+		}
+	}
+	assert.Equal(t, egtsPcOk, readTestAuthResult(t, conn))
+
+	_, _ = conn.Write(egtsPkgPosDataBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		rec := (*resp.SDR.(*ServiceDataSet))[0]
+		assert.Equal(t, egtsPcOk, rec.RecordDataSet[0].SubrecordData.(*SrResponse).RecordStatus)
+	}
+	assert.Equal(t, 3, handled)
+}
+
+func TestServer_AuthResultDispatcherIdentity(t *testing.T) {
+	dispatcher := SrDispatcherIdentity{DispatcherType: 0, DispatcherID: 1024, Description: "platform"}
+	conn := startTestServer(&Server{RequireAuth: true, DispatcherIdentity: &dispatcher})
+	defer conn.Close()
+
+	identity := testEgtsSrTermIdentity
+	pkgBytes, err := WrapSubrecord(AuthService, &identity, 1).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, _ = conn.Write(pkgBytes)
+	readTestResponse(t, conn)
+
+	rawPkg, err := ReadPackage(conn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	pkg := Package{}
+	if _, err = pkg.Decode(rawPkg); assert.NoError(t, err) {
+		rds := (*pkg.ServicesFrameData.(*ServiceDataSet))[0].RecordDataSet
+		if assert.Len(t, rds, 2) {
+			assert.Equal(t, &SrResultCode{ResultCode: egtsPcOk}, rds[0].SubrecordData)
+			assert.Equal(t, &dispatcher, rds[1].SubrecordData)
+		}
+	}
+}
+
+// testTLSConfig формирует самоподписанный сертификат для localhost
+func testTLSConfig(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{certDER}, PrivateKey: key}}}
+}
+
+func TestServer_ServeTLSConn(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	go (&Server{}).ServeConn(tls.Server(serverConn, testTLSConfig(t)))
+
+	conn := tls.Client(clientConn, &tls.Config{InsecureSkipVerify: true})
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	_, err := conn.Write(egtsPkgPosDataBytes)
+	if assert.NoError(t, err) {
+		if resp := readTestResponse(t, conn); resp != nil {
+			assert.Equal(t, uint16(138), resp.ResponsePacketID)
+		}
+	}
+}
+
+func TestServer_ListenAndServeTLSNoCert(t *testing.T) {
+	assert.Error(t, (&Server{}).ListenAndServeTLS("127.0.0.1:0", "/nonexistent.crt", "/nonexistent.key"))
+}
+
+func TestServer_HandlerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	conn := startTestServer(&Server{
+		HandlerTimeout: 50 * time.Millisecond,
+		Handler: func(rec *ServiceDataRecord) (uint8, Directive) {
+			<-release
+			return egtsPcOk, Continue
+		},
+	})
+	defer conn.Close()
+
+	start := time.Now()
+	_, _ = conn.Write(egtsPkgPosDataBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		rec := (*resp.SDR.(*ServiceDataSet))[0]
+		assert.Equal(t, egtsPcInProgress, rec.RecordDataSet[0].SubrecordData.(*SrResponse).RecordStatus)
+	}
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestServer_ContextHandlerTimeout(t *testing.T) {
+	canceled := make(chan bool, 1)
+	conn := startTestServer(&Server{
+		HandlerTimeout: 50 * time.Millisecond,
+		ContextHandler: func(ctx context.Context, rec *ServiceDataRecord) (uint8, Directive) {
+			_, hasDeadline := ctx.Deadline()
+			<-ctx.Done()
+			canceled <- hasDeadline
+			return egtsPcOk, Continue
+		},
+	})
+	defer conn.Close()
+
+	_, _ = conn.Write(egtsPkgPosDataBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		rec := (*resp.SDR.(*ServiceDataSet))[0]
+		assert.Equal(t, egtsPcInProgress, rec.RecordDataSet[0].SubrecordData.(*SrResponse).RecordStatus)
+	}
+
+	// по истечении срока обработчик узнает об отмене из контекста
+	select {
+	case hasDeadline := <-canceled:
+		assert.True(t, hasDeadline)
+	case <-time.After(time.Second):
+		t.Error("контекст обработчика не отменен")
+	}
+}
+
+func TestServer_StoreHandler(t *testing.T) {
+	stored := 0
+	fail := true
+	conn := startTestServer(&Server{
+		Handler: StoreHandler(func(rec *ServiceDataRecord) error {
+			if fail {
+				return io.ErrShortWrite
+			}
+			stored++
+			return nil
+		}, 0),
+	})
+	defer conn.Close()
+
+	_, _ = conn.Write(egtsPkgPosDataBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		rec := (*resp.SDR.(*ServiceDataSet))[0]
+		status := rec.RecordDataSet[0].SubrecordData.(*SrResponse).RecordStatus
+		assert.Equal(t, egtsPcNoResAvail, status)
+		assert.True(t, (&PtResponse{ProcessingResult: status}).ShouldRetry())
+	}
+	assert.Equal(t, 0, stored)
+
+	// ответ отправляется только после сохранения записи
+	fail = false
+	_, _ = conn.Write(egtsPkgPosDataBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		rec := (*resp.SDR.(*ServiceDataSet))[0]
+		assert.Equal(t, egtsPcOk, rec.RecordDataSet[0].SubrecordData.(*SrResponse).RecordStatus)
+		assert.Equal(t, 1, stored)
+	}
+}
+
+func TestServer_ListenAndServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	received := make(chan uint16, 1)
+	srv := &Server{}
+	go func() {
+		_ = srv.ListenAndServe(addr, func(rec *ServiceDataRecord) (uint8, Directive) {
+			if pos, ok := rec.RecordDataSet[0].SubrecordData.(*SrPosData); ok {
+				received <- pos.Speed
+			}
+			return egtsPcOk, Continue
+		})
+	}()
+
+	var conn net.Conn
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("tcp", addr); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	identity := testEgtsSrTermIdentity
+	identityBytes, err := WrapSubrecord(AuthService, &identity, 1).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, _ = conn.Write(identityBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		assert.Equal(t, uint16(1), resp.ResponsePacketID)
+	}
+	assert.Equal(t, egtsPcOk, readTestAuthResult(t, conn))
+
+	_, _ = conn.Write(egtsPkgPosDataBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		assert.Equal(t, uint16(138), resp.ResponsePacketID)
+		assert.Equal(t, egtsPcOk, resp.ProcessingResult)
+
+		rec := (*resp.SDR.(*ServiceDataSet))[0]
+		assert.Equal(t, &SrResponse{ConfirmedRecordNumber: 97, RecordStatus: egtsPcOk}, rec.RecordDataSet[0].SubrecordData)
+	}
+	assert.Equal(t, uint16(200), <-received)
+	// обработчик ListenAndServe не подменяет обработчик сервера
+	assert.Nil(t, srv.Handler)
+}
+
+func TestServer_KeepUnknownSubrecords(t *testing.T) {
+	pos := testEgtsSrPosData
+	pkgBytes, err := NewAppdataPackage(1, ServiceDataSet{
+		NewServiceDataRecord(1, 0, TeledataService, RecordDataSet{
+			{SubrecordData: &pos},
+			{SubrecordData: &RawSubrecord{SRT: 0xEE, Data: []byte{0x01, 0x02, 0x03}}},
+		}),
+	}).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// по умолчанию пакет с неизвестной подзаписью отклоняется
+	conn := startTestServer(&Server{})
+	_, _ = conn.Write(pkgBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		assert.Equal(t, egtsPcIncDataform, resp.ProcessingResult)
+	}
+	conn.Close()
+
+	// режим разбора сервера применяется к каждому пакету соединения
+	received := make(chan *RawSubrecord, 1)
+	conn = startTestServer(&Server{
+		KeepUnknownSubrecords: true,
+		Handler: func(rec *ServiceDataRecord) (uint8, Directive) {
+			if raw, ok := rec.RecordDataSet[1].SubrecordData.(*RawSubrecord); ok {
+				received <- raw
+			}
+			return egtsPcOk, Continue
+		},
+	})
+	defer conn.Close()
+
+	_, _ = conn.Write(pkgBytes)
+	if resp := readTestResponse(t, conn); resp != nil {
+		assert.Equal(t, egtsPcOk, resp.ProcessingResult)
+	}
+	select {
+	case raw := <-received:
+		assert.Equal(t, byte(0xEE), raw.SRT)
+	default:
+		t.Error("подзапись не передана обработчику")
+	}
+}
@@ -24,6 +24,12 @@ type ServiceDataRecord struct {
 	SourceServiceType        byte   `json:"SST"`
 	RecipientServiceType     byte   `json:"RST"`
 	RecordDataSet            `json:"RD"`
+
+	// RawFlags байт флагов записи в том виде, в каком он получен (SSOD, RSOD, GRP, RPP, TMFE, EVFE, OBFE).
+	// Заполняется при разборе, при кодировании не используется
+	RawFlags byte `json:"-"`
+	// CanonicalOrder при кодировании упорядочить подзаписи (см. RecordDataSet.CanonicalOrder)
+	CanonicalOrder bool `json:"-"`
 }
 
 //ServiceDataSet набор последовательных записей с информаций
@@ -31,9 +37,15 @@ type ServiceDataSet []ServiceDataRecord
 
 //Decode разбирает байты в структуру подзаписи
 func (s *ServiceDataSet) Decode(serviceDS []byte) error {
+	return s.decode(serviceDS, decodeMode{})
+}
+
+// decode разбирает записи с учетом режима разбора пакета
+func (s *ServiceDataSet) decode(serviceDS []byte, mode decodeMode) error {
 	var (
-		err   error
-		flags byte
+		err       error
+		flags     byte
+		corrected bool
 	)
 	buf := bytes.NewReader(serviceDS)
 
@@ -41,18 +53,19 @@ func (s *ServiceDataSet) Decode(serviceDS []byte) error {
 		sdr := ServiceDataRecord{}
 		tmpIntBuf := make([]byte, 2)
 		if _, err = buf.Read(tmpIntBuf); err != nil {
-			return fmt.Errorf("Не удалось получить длину записи SDR: %v", err)
+			return newParseError(len(serviceDS)-buf.Len(), "Не удалось получить длину записи SDR: %v", err)
 		}
 		sdr.RecordLength = binary.LittleEndian.Uint16(tmpIntBuf)
 
 		if _, err = buf.Read(tmpIntBuf); err != nil {
-			return fmt.Errorf("Не удалось получить номер записи SDR: %v", err)
+			return newParseError(len(serviceDS)-buf.Len(), "Не удалось получить номер записи SDR: %v", err)
 		}
 		sdr.RecordNumber = binary.LittleEndian.Uint16(tmpIntBuf)
 
 		if flags, err = buf.ReadByte(); err != nil {
-			return fmt.Errorf("Не удалось считать байт флагов SDR: %v", err)
+			return newParseError(len(serviceDS)-buf.Len(), "Не удалось считать байт флагов SDR: %v", err)
 		}
+		sdr.RawFlags = flags
 		flagBits := fmt.Sprintf("%08b", flags)
 		sdr.SourceServiceOnDevice = flagBits[:1]
 		sdr.RecipientServiceOnDevice = flagBits[1:2]
@@ -65,7 +78,7 @@ func (s *ServiceDataSet) Decode(serviceDS []byte) error {
 		if sdr.ObjectIDFieldExists == "1" {
 			oid := make([]byte, 4)
 			if _, err := buf.Read(oid); err != nil {
-				return fmt.Errorf("Не удалось получить идентификатор объекта SDR: %v", err)
+				return newParseError(len(serviceDS)-buf.Len(), "Не удалось получить идентификатор объекта SDR: %v", err)
 			}
 			sdr.ObjectIdentifier = binary.LittleEndian.Uint32(oid)
 		}
@@ -73,7 +86,7 @@ func (s *ServiceDataSet) Decode(serviceDS []byte) error {
 		if sdr.EventIDFieldExists == "1" {
 			event := make([]byte, 4)
 			if _, err := buf.Read(event); err != nil {
-				return fmt.Errorf("Не удалось получить идентификатор события SDR: %v", err)
+				return newParseError(len(serviceDS)-buf.Len(), "Не удалось получить идентификатор события SDR: %v", err)
 			}
 			sdr.EventIdentifier = binary.LittleEndian.Uint32(event)
 		}
@@ -81,30 +94,34 @@ func (s *ServiceDataSet) Decode(serviceDS []byte) error {
 		if sdr.TimeFieldExists == "1" {
 			tm := make([]byte, 4)
 			if _, err := buf.Read(tm); err != nil {
-				return fmt.Errorf("Не удалось получить время формирования записи на стороне отправителя SDR: %v", err)
+				return newParseError(len(serviceDS)-buf.Len(), "Не удалось получить время формирования записи на стороне отправителя SDR: %v", err)
 			}
 			sdr.Time = binary.LittleEndian.Uint32(tm)
 		}
 
 		if sdr.SourceServiceType, err = buf.ReadByte(); err != nil {
-			return fmt.Errorf("Не удалось считать идентификатор тип сервиса-отправителя SDR: %v", err)
+			return newParseError(len(serviceDS)-buf.Len(), "Не удалось считать идентификатор тип сервиса-отправителя SDR: %v", err)
 		}
 
 		if sdr.RecipientServiceType, err = buf.ReadByte(); err != nil {
-			return fmt.Errorf("Не удалось считать идентификатор тип сервиса-получателя SDR: %v", err)
+			return newParseError(len(serviceDS)-buf.Len(), "Не удалось считать идентификатор тип сервиса-получателя SDR: %v", err)
 		}
 
 		if buf.Len() != 0 {
 			rds := RecordDataSet{}
 			rdsBytes := make([]byte, sdr.RecordLength)
+			rdsOffset := len(serviceDS) - buf.Len()
 			if _, err = buf.Read(rdsBytes); err != nil {
-				return err
+				return newParseError(rdsOffset, "Не удалось получить данные записи SDR: %v", err)
 			}
 
-			if err = rds.Decode(rdsBytes); err != nil {
-				return err
+			if corrected, err = rds.decode(rdsBytes, mode); err != nil {
+				return parseErrorAt(rdsOffset, err)
 			}
 			sdr.RecordDataSet = rds
+			if corrected {
+				sdr.RecordLength = rds.Length()
+			}
 		}
 
 		*s = append(*s, sdr)
@@ -122,7 +139,15 @@ func (s *ServiceDataSet) Encode() ([]byte, error) {
 	buf := new(bytes.Buffer)
 
 	for _, sdr := range *s {
-		rd, err := sdr.RecordDataSet.Encode()
+		rds := sdr.RecordDataSet
+		if sdr.CanonicalOrder {
+			var err error
+			if rds, err = rds.CanonicalOrder(); err != nil {
+				return result, err
+			}
+		}
+
+		rd, err := rds.Encode()
 		if err != nil {
 			return result, err
 		}
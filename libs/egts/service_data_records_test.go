@@ -17,6 +17,7 @@ func TestServiceDataRecord_Encode(t *testing.T) {
 			TimeFieldExists:          "0",
 			EventIDFieldExists:       "0",
 			ObjectIDFieldExists:      "1",
+			RawFlags:                 0x99,
 			ObjectIdentifier:         133552,
 			SourceServiceType:        2,
 			RecipientServiceType:     2,
@@ -43,6 +44,7 @@ func TestServiceDataRecord_Decode(t *testing.T) {
 			TimeFieldExists:          "0",
 			EventIDFieldExists:       "0",
 			ObjectIDFieldExists:      "1",
+			RawFlags:                 0x99,
 			ObjectIdentifier:         133552,
 			SourceServiceType:        2,
 			RecipientServiceType:     2,
@@ -53,3 +55,55 @@ func TestServiceDataRecord_Decode(t *testing.T) {
 		assert.Equal(t, sdr, testServiceDataRecord)
 	}
 }
+
+func TestServiceDataRecord_RawFlags(t *testing.T) {
+	pos := testEgtsSrPosData
+	rec := NewServiceDataRecord(1, 1, TeledataService, RecordDataSet{RecordData{SubrecordData: &pos}})
+	rec.Group = "1"
+	rec.RecordProcessingPriority = "10"
+
+	sdsBytes, err := (&ServiceDataSet{rec}).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sds := ServiceDataSet{}
+	if assert.NoError(t, sds.Decode(sdsBytes)) {
+		// байт флагов идет после RL и RN
+		assert.Equal(t, sdsBytes[4], sds[0].RawFlags)
+		assert.Equal(t, byte(0xB1), sds[0].RawFlags)
+	}
+}
+
+func TestServiceDataRecord_PosDataLayout(t *testing.T) {
+	pos := testEgtsSrPosData
+	rec := NewServiceDataRecord(0x0102, 0x0A0B0C0D, TeledataService, RecordDataSet{RecordData{SubrecordData: &pos}})
+	rec.EventIDFieldExists, rec.EventIdentifier = "1", 0x11223344
+	rec.TimeFieldExists, rec.Time = "1", 0x55667788
+
+	sdsBytes, err := (&ServiceDataSet{rec}).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []byte{byte(len(testEgtsSrPosDataBytes) + 3), 0x00}, sdsBytes[0:2])
+	assert.Equal(t, []byte{0x02, 0x01}, sdsBytes[2:4])
+	// SSOD=1, TMFE=1, EVFE=1, OBFE=1
+	assert.Equal(t, byte(0x87), sdsBytes[4])
+	// необязательные поля идут в порядке OID, EVID, TM
+	assert.Equal(t, []byte{0x0D, 0x0C, 0x0B, 0x0A}, sdsBytes[5:9])
+	assert.Equal(t, []byte{0x44, 0x33, 0x22, 0x11}, sdsBytes[9:13])
+	assert.Equal(t, []byte{0x88, 0x77, 0x66, 0x55}, sdsBytes[13:17])
+	assert.Equal(t, []byte{TeledataService, TeledataService}, sdsBytes[17:19])
+	assert.Equal(t, []byte{SrPosDataType, byte(len(testEgtsSrPosDataBytes)), 0x00}, sdsBytes[19:22])
+	assert.Equal(t, testEgtsSrPosDataBytes, sdsBytes[22:])
+
+	// без OBFE, EVFE и TMFE необязательные поля не передаются
+	rec.ObjectIDFieldExists, rec.EventIDFieldExists, rec.TimeFieldExists = "0", "0", "0"
+	sdsBytes, err = (&ServiceDataSet{rec}).Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, byte(0x80), sdsBytes[4])
+		assert.Equal(t, []byte{TeledataService, TeledataService, SrPosDataType}, sdsBytes[5:8])
+		assert.Len(t, sdsBytes, 7+3+len(testEgtsSrPosDataBytes))
+	}
+}
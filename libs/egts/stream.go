@@ -0,0 +1,374 @@
+package egts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+//ReadPackage считывает из потока один пакет ЕГТС целиком, используя длины HL и FDL из заголовка.
+//Если поток закончился до начала пакета возвращается io.EOF, если внутри пакета - io.ErrUnexpectedEOF
+func ReadPackage(r io.Reader) ([]byte, error) {
+	header := make([]byte, DEFAULT_HEADER_LEN)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	headerLen := int(header[3])
+	if headerLen < DEFAULT_HEADER_LEN {
+		return nil, fmt.Errorf("Некорректная длина заголовка пакета: %d", headerLen)
+	}
+
+	// длина пакета равна длине заголовка (HL) + длина тела (FDL) + CRC тела 2 байта, если есть FDL
+	pkgLen := headerLen
+	if bodyLen := int(binary.LittleEndian.Uint16(header[5:7])); bodyLen > 0 {
+		pkgLen += bodyLen + 2
+	}
+
+	pkg := make([]byte, pkgLen)
+	copy(pkg, header)
+	if _, err := io.ReadFull(r, pkg[len(header):]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return pkg, nil
+}
+
+//Decoder последовательно разбирает пакеты из потока, например TCP соединения, в котором границы пакетов не
+//совпадают с границами операций чтения. Данные следующего пакета остаются в буфере декодера. Strict и
+//KeepUnknownSubrecords задают одноименные режимы разбора каждого пакета (см. Package)
+type Decoder struct {
+	Strict                bool
+	KeepUnknownSubrecords bool
+
+	r *bufio.Reader
+}
+
+//NewDecoder создает декодер, читающий пакеты из r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+//Decode считывает и разбирает очередной пакет. В конце потока возвращается io.EOF, при обрыве внутри
+//пакета - io.ErrUnexpectedEOF, пакет при ошибках чтения равен nil. При ошибке разбора возвращается частично
+//разобранный пакет, код результата обработки для ответа доступен через ResultCode
+func (d *Decoder) Decode() (*Package, error) {
+	rawPkg, err := ReadPackage(d.r)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &Package{Strict: d.Strict, KeepUnknownSubrecords: d.KeepUnknownSubrecords}
+	_, err = pkg.Decode(rawPkg)
+	return pkg, err
+}
+
+//ParseAll последовательно разбирает пакеты, записанные в data друг за другом, например содержимое дампа
+//или прочитанный из соединения фрагмент. Возвращает разобранные пакеты и число использованных байт.
+//Неполный пакет в конце data не считается ошибкой: его байты не входят в использованные, и разбор
+//можно продолжить, дополнив их новыми данными. Ошибка разбора возвращается как *ParseError со смещением от
+//начала data, вместе с пакетами, разобранными до нее
+func ParseAll(data []byte) ([]*Package, int, error) {
+	var pkgs []*Package
+	offset := 0
+	for offset < len(data) {
+		rawPkg, err := ReadPackage(bytes.NewReader(data[offset:]))
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			break
+		}
+		if err != nil {
+			return pkgs, offset, newParseError(offset+3, "%v", err)
+		}
+
+		pkg := &Package{}
+		if _, err = pkg.Decode(rawPkg); err != nil {
+			return pkgs, offset, parseErrorAt(offset, err)
+		}
+		pkgs = append(pkgs, pkg)
+		offset += len(rawPkg)
+	}
+	return pkgs, offset, nil
+}
+
+//Encoder кодирует пакеты и записывает их в поток целиком
+type Encoder struct {
+	// PIDs счетчик идентификаторов пакетов. Если задан, каждому записываемому пакету присваивается его очередное
+	// значение, иначе PID пакета не меняется. PID входит в заголовок и контрольную сумму, поэтому присваивается
+	// до кодирования: если пакет не удалось закодировать, значение счетчика все равно расходуется
+	PIDs *PidCounter
+
+	w io.Writer
+}
+
+//NewEncoder создает кодировщик, записывающий пакеты в w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+//Encode кодирует пакет и записывает его в поток. Неполная запись повторяется для оставшихся байт
+func (e *Encoder) Encode(p *Package) error {
+	if e.PIDs != nil {
+		p.PacketIdentifier = e.PIDs.Next()
+	}
+
+	pkgBytes, err := p.Encode()
+	if err != nil {
+		return err
+	}
+
+	for len(pkgBytes) > 0 {
+		n, err := e.w.Write(pkgBytes)
+		if err != nil {
+			return fmt.Errorf("Не удалось записать пакет %d: %v", p.PacketIdentifier, err)
+		}
+		if n == 0 {
+			return fmt.Errorf("Не удалось записать пакет %d: %v", p.PacketIdentifier, io.ErrShortWrite)
+		}
+		pkgBytes = pkgBytes[n:]
+	}
+	return nil
+}
+
+//StreamPositions разбирает пакеты из потока и передает в канал все подзаписи EGTS_SR_POS_DATA по мере
+//поступления. При достижении конца потока каналы закрываются, ошибка разбора передается в канал ошибок.
+//После отмены ctx разбор прекращается, даже если отметки никто не читает, а в канал ошибок передается
+//ctx.Err(). Уже начатое чтение из r отменой не прерывается, для этого r нужно закрыть
+func StreamPositions(ctx context.Context, r io.Reader) (<-chan *SrPosData, <-chan error) {
+	positions := make(chan *SrPosData)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(positions)
+		defer close(errs)
+
+		for {
+			if err := ctx.Err(); err != nil {
+				errs <- err
+				return
+			}
+
+			rawPkg, err := ReadPackage(r)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			pkg := Package{}
+			if _, err = pkg.Decode(rawPkg); err != nil {
+				errs <- err
+				return
+			}
+
+			sds, ok := pkg.ServicesFrameData.(*ServiceDataSet)
+			if !ok {
+				continue
+			}
+
+			for _, rec := range *sds {
+				for _, subRec := range rec.RecordDataSet {
+					pos, ok := subRec.SubrecordData.(*SrPosData)
+					if !ok {
+						continue
+					}
+
+					select {
+					case positions <- pos:
+					case <-ctx.Done():
+						errs <- ctx.Err()
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return positions, errs
+}
+
+// positionKey ключ сравнения отметок при удалении повторов
+type positionKey struct {
+	lat, lon float64
+	bucket   time.Time
+}
+
+//DedupePositions передает из in отметки, отбрасывая подряд идущие повторы: отметки с теми же координатами
+//(с учетом полушарий), время навигации которых попадает в тот же интервал bucket. При bucket не больше 0
+//повтором считается только отметка с тем же временем. Выходной канал закрывается после закрытия in
+func DedupePositions(in <-chan *SrPosData, bucket time.Duration) <-chan *SrPosData {
+	out := make(chan *SrPosData)
+
+	go func() {
+		defer close(out)
+
+		var (
+			last    positionKey
+			hasLast bool
+		)
+		for pos := range in {
+			key := positionKey{bucket: pos.NavigationTime}
+			key.lat, key.lon = signedCoordinates(pos)
+			if bucket > 0 {
+				key.bucket = pos.NavigationTime.Truncate(bucket)
+			}
+
+			if hasLast && key.lat == last.lat && key.lon == last.lon && key.bucket.Equal(last.bucket) {
+				continue
+			}
+			last, hasLast = key, true
+			out <- pos
+		}
+	}()
+
+	return out
+}
+
+//TimeOrderViolation нарушение порядка времени навигации: отметка с номером Index оказалась раньше предыдущей
+type TimeOrderViolation struct {
+	Index int
+	Prev  time.Time
+	Cur   time.Time
+}
+
+//StreamValidator проверяет, что время навигации последовательных EGTS_SR_POS_DATA не идет назад.
+//Отставание не больше Tolerance нарушением не считается
+type StreamValidator struct {
+	Tolerance  time.Duration
+	Violations []TimeOrderViolation
+
+	last  time.Time
+	index int
+}
+
+//Check проверяет очередную отметку, возвращает false, если ее время меньше времени предыдущей
+func (v *StreamValidator) Check(pos *SrPosData) bool {
+	defer func() { v.index++ }()
+
+	ok := v.index == 0 || !pos.NavigationTime.Before(v.last.Add(-v.Tolerance))
+	if !ok {
+		v.Violations = append(v.Violations, TimeOrderViolation{Index: v.index, Prev: v.last, Cur: pos.NavigationTime})
+	}
+
+	if pos.NavigationTime.After(v.last) || v.index == 0 {
+		v.last = pos.NavigationTime
+	}
+	return ok
+}
+
+//PositionJump скачок координат: для перемещения от последней правдоподобной отметки к отметке с номером Index
+//потребовалась бы скорость ImpliedSpeed (км/ч)
+type PositionJump struct {
+	Index        int
+	ImpliedSpeed float64
+}
+
+// defaultMaxJumpRejections число подряд несогласованных отметок, после которого JumpValidator меняет опорную
+// отметку, если MaxRejections не задан
+const defaultMaxJumpRejections = 3
+
+//JumpValidator выявляет физически невозможные скачки координат из-за сбоев навигационного приемника по скорости,
+//вычисленной из расстояния и времени между последовательными отметками. Отметка со скачком не становится опорной,
+//поэтому следующая сравнивается с последней правдоподобной. Если MaxRejections отметок подряд (по умолчанию 3)
+//не согласуются с опорной, ошибочной считается сама опорная отметка, например первая отметка после холодного
+//старта приемника: последняя из них принимается и становится опорной. Отметки без достоверного решения не
+//проверяются
+type JumpValidator struct {
+	MaxImpliedSpeed float64
+	MaxRejections   int
+	Jumps           []PositionJump
+
+	last     *SrPosData
+	rejected int
+	index    int
+}
+
+//Check проверяет очередную отметку, возвращает false, если скорость перемещения к ней превышает MaxImpliedSpeed
+func (v *JumpValidator) Check(pos *SrPosData) bool {
+	defer func() { v.index++ }()
+
+	if pos.FixType() == FixNone {
+		return true
+	}
+	if v.last == nil {
+		v.last = pos
+		return true
+	}
+
+	speed, ok := AverageSpeed(v.last, pos)
+	if !ok {
+		// без приращения времени скорость не определена
+		return true
+	}
+	if speed > v.MaxImpliedSpeed {
+		v.rejected++
+		if v.rejected < v.maxRejections() {
+			v.Jumps = append(v.Jumps, PositionJump{Index: v.index, ImpliedSpeed: speed})
+			return false
+		}
+	}
+
+	v.last = pos
+	v.rejected = 0
+	return true
+}
+
+// maxRejections число подряд несогласованных отметок, после которого меняется опорная отметка
+func (v *JumpValidator) maxRejections() int {
+	if v.MaxRejections <= 0 {
+		return defaultMaxJumpRejections
+	}
+	return v.MaxRejections
+}
+
+//SubrecordKind сочетание типа сервиса записи и типа подзаписи
+type SubrecordKind struct {
+	ServiceType   byte
+	SubrecordType byte
+}
+
+//ProfileCapture разбирает все пакеты потока и подсчитывает, сколько раз встретилось каждое сочетание
+//сервиса и типа подзаписи. Позволяет узнать, какие данные терминал передает на самом деле
+func ProfileCapture(r io.Reader) (map[SubrecordKind]int, error) {
+	profile := map[SubrecordKind]int{}
+
+	for {
+		rawPkg, err := ReadPackage(r)
+		if err == io.EOF {
+			return profile, nil
+		}
+		if err != nil {
+			return profile, err
+		}
+
+		pkg := Package{}
+		if _, err = pkg.Decode(rawPkg); err != nil {
+			return profile, err
+		}
+
+		var sds *ServiceDataSet
+		switch frame := pkg.ServicesFrameData.(type) {
+		case *ServiceDataSet:
+			sds = frame
+		case *PtResponse:
+			sds, _ = frame.SDR.(*ServiceDataSet)
+		}
+		if sds == nil {
+			continue
+		}
+
+		for _, rec := range *sds {
+			for _, subRec := range rec.RecordDataSet {
+				profile[SubrecordKind{rec.SourceServiceType, subRec.SubrecordType}]++
+			}
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package egts
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestReadPackage(t *testing.T) {
+	stream := bytes.NewReader(append(append([]byte{}, egtsPkgPosDataBytes...), goldenRoutedResponseBytes...))
+
+	pkg, err := ReadPackage(stream)
+	if assert.NoError(t, err) {
+		assert.Equal(t, egtsPkgPosDataBytes, pkg)
+	}
+
+	pkg, err = ReadPackage(stream)
+	if assert.NoError(t, err) {
+		assert.Equal(t, goldenRoutedResponseBytes, pkg)
+	}
+
+	_, err = ReadPackage(stream)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = ReadPackage(bytes.NewReader(egtsPkgPosDataBytes[:20]))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestStreamPositions(t *testing.T) {
+	r, w := io.Pipe()
+	go func() {
+		for i := 0; i < 3; i++ {
+			_, _ = w.Write(egtsPkgPosDataBytes)
+		}
+		_, _ = w.Write(goldenRoutedResponseBytes)
+		_ = w.Close()
+	}()
+
+	positions, errs := StreamPositions(context.Background(), r)
+
+	count := 0
+	for pos := range positions {
+		assert.Equal(t, uint16(200), pos.Speed)
+		count++
+	}
+	assert.Equal(t, 3, count)
+	assert.NoError(t, <-errs)
+}
+
+func TestStreamPositions_Cancel(t *testing.T) {
+	stream := bytes.Repeat(egtsPkgPosDataBytes, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	positions, errs := StreamPositions(ctx, bytes.NewReader(stream))
+
+	// получатель забирает одну отметку и прекращает чтение
+	<-positions
+	cancel()
+
+	select {
+	case err := <-errs:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("разбор не прекращен после отмены")
+	}
+	for range positions {
+	}
+}
+
+func TestStreamValidator(t *testing.T) {
+	start := testEgtsSrPosData.NavigationTime
+	stream := []SrPosData{
+		{NavigationTime: start},
+		{NavigationTime: start.Add(10 * time.Second)},
+		{NavigationTime: start.Add(5 * time.Second)},
+		{NavigationTime: start.Add(9 * time.Second)},
+		{NavigationTime: start.Add(20 * time.Second)},
+	}
+
+	v := StreamValidator{}
+	results := []bool{}
+	for i := range stream {
+		results = append(results, v.Check(&stream[i]))
+	}
+
+	assert.Equal(t, []bool{true, true, false, false, true}, results)
+	if assert.Len(t, v.Violations, 2) {
+		assert.Equal(t, TimeOrderViolation{Index: 2, Prev: start.Add(10 * time.Second), Cur: start.Add(5 * time.Second)}, v.Violations[0])
+	}
+
+	tolerant := StreamValidator{Tolerance: 2 * time.Second}
+	tolerant.Check(&stream[1])
+	assert.True(t, tolerant.Check(&stream[3]))
+	assert.False(t, tolerant.Check(&stream[2]))
+}
+
+func TestProfileCapture(t *testing.T) {
+	identity := testEgtsSrTermIdentity
+	pos := testEgtsSrPosData
+	combined, err := NewIdentityWithPositionPackage(2, 1, &identity, &pos).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	capture := bytes.Buffer{}
+	capture.Write(egtsPkgPosDataBytes)
+	capture.Write(combined)
+	capture.Write(goldenRoutedResponseBytes)
+
+	profile, err := ProfileCapture(&capture)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[SubrecordKind]int{
+			{TeledataService, SrPosDataType}:  2,
+			{AuthService, SrTermIdentityType}: 1,
+		}, profile)
+	}
+}
+
+func TestDedupePositions(t *testing.T) {
+	start := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	parked := func(offset time.Duration) *SrPosData {
+		return NewSrPosData(start.Add(offset), 55.75, 37.61, 0)
+	}
+
+	in := make(chan *SrPosData)
+	go func() {
+		defer close(in)
+		// стоянка: пять одинаковых отметок в пределах минуты, затем начало движения и снова стоянка
+		for i := 0; i < 5; i++ {
+			in <- parked(time.Duration(i) * 10 * time.Second)
+		}
+		in <- NewSrPosData(start.Add(55*time.Second), 55.76, 37.61, 20)
+		in <- parked(58 * time.Second)
+		in <- parked(70 * time.Second)
+		in <- parked(80 * time.Second)
+	}()
+
+	var result []*SrPosData
+	for pos := range DedupePositions(in, time.Minute) {
+		result = append(result, pos)
+	}
+
+	if assert.Len(t, result, 4) {
+		assert.Equal(t, start, result[0].NavigationTime)
+		assert.Equal(t, uint16(20), result[1].Speed)
+		assert.Equal(t, start.Add(58*time.Second), result[2].NavigationTime)
+		assert.Equal(t, start.Add(70*time.Second), result[3].NavigationTime)
+	}
+}
+
+func TestJumpValidator(t *testing.T) {
+	start := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	track := []*SrPosData{
+		NewSrPosData(start, 55.7500, 37.6100, 60),
+		NewSrPosData(start.Add(10*time.Second), 55.7515, 37.6100, 60),
+		// скачок примерно на 50 км за 10 секунд
+		NewSrPosData(start.Add(20*time.Second), 56.2000, 37.6100, 60),
+		NewSrPosData(start.Add(30*time.Second), 55.7545, 37.6100, 60),
+	}
+	v := JumpValidator{MaxImpliedSpeed: 300}
+	var accepted []bool
+	for _, pos := range track {
+		accepted = append(accepted, v.Check(pos))
+	}
+
+	assert.Equal(t, []bool{true, true, false, true}, accepted)
+	if assert.Len(t, v.Jumps, 1) {
+		assert.Equal(t, 2, v.Jumps[0].Index)
+		assert.True(t, v.Jumps[0].ImpliedSpeed > 10000)
+	}
+}
+
+func TestJumpValidator_BadAnchor(t *testing.T) {
+	start := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	track := []*SrPosData{
+		// первая отметка после холодного старта приемника примерно в 50 км от реального положения
+		NewSrPosData(start, 56.2000, 37.6100, 60),
+		NewSrPosData(start.Add(10*time.Second), 55.7500, 37.6100, 60),
+		NewSrPosData(start.Add(20*time.Second), 55.7515, 37.6100, 60),
+		NewSrPosData(start.Add(30*time.Second), 55.7530, 37.6100, 60),
+		NewSrPosData(start.Add(40*time.Second), 55.7545, 37.6100, 60),
+	}
+	v := JumpValidator{MaxImpliedSpeed: 300}
+	var accepted []bool
+	for _, pos := range track {
+		accepted = append(accepted, v.Check(pos))
+	}
+
+	// после трех отклоненных подряд отметок опорной становится последняя из них
+	assert.Equal(t, []bool{true, false, false, true, true}, accepted)
+	if assert.Len(t, v.Jumps, 2) {
+		assert.Equal(t, 1, v.Jumps[0].Index)
+		assert.Equal(t, 2, v.Jumps[1].Index)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	stream := append(append([]byte{}, egtsPkgPosDataBytes...), goldenRoutedResponseBytes...)
+
+	// чтение по одному байту: границы чтения не совпадают с границами пакетов
+	dec := NewDecoder(iotest.OneByteReader(bytes.NewReader(stream)))
+
+	pkg, err := dec.Decode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint16(138), pkg.PacketIdentifier)
+		assert.Equal(t, byte(PtAppdataPacket), pkg.PacketType)
+	}
+
+	pkg, err = dec.Decode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, byte(PtResponsePacket), pkg.PacketType)
+	}
+
+	_, err = dec.Decode()
+	assert.Equal(t, io.EOF, err)
+
+	// второй пакет оборван внутри заголовка
+	dec = NewDecoder(bytes.NewReader(stream[:len(egtsPkgPosDataBytes)+5]))
+	_, err = dec.Decode()
+	assert.NoError(t, err)
+	_, err = dec.Decode()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+// shortWriter записывает не больше трех байт за вызов
+type shortWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > 3 {
+		p = p[:3]
+	}
+	return w.buf.Write(p)
+}
+
+func TestEncoder(t *testing.T) {
+	var out shortWriter
+	enc := NewEncoder(&out)
+	enc.PIDs = NewPidCounter(65534)
+
+	pos := testEgtsSrPosData
+	for i := 0; i < 3; i++ {
+		if !assert.NoError(t, enc.Encode(WrapSubrecord(TeledataService, &pos, 0))) {
+			return
+		}
+	}
+
+	dec := NewDecoder(&out.buf)
+	for _, pid := range []uint16{65534, 65535, 0} {
+		pkg, err := dec.Decode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, pid, pkg.PacketIdentifier)
+		}
+	}
+	_, err := dec.Decode()
+	assert.Equal(t, io.EOF, err)
+}
+
+// zeroWriter ничего не записывает и не возвращает ошибку
+type zeroWriter struct{}
+
+func (zeroWriter) Write(p []byte) (int, error) {
+	return 0, nil
+}
+
+func TestEncoder_ZeroWrite(t *testing.T) {
+	pos := testEgtsSrPosData
+	err := NewEncoder(zeroWriter{}).Encode(WrapSubrecord(TeledataService, &pos, 1))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), io.ErrShortWrite.Error())
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	var data []byte
+	for i := 0; i < 2; i++ {
+		data = append(data, egtsPkgPosDataBytes...)
+	}
+	data = append(data, goldenRoutedResponseBytes...)
+	complete := len(data)
+	data = append(data, egtsPkgPosDataBytes[:15]...)
+
+	pkgs, consumed, err := ParseAll(data)
+	if assert.NoError(t, err) {
+		assert.Equal(t, complete, consumed)
+		if assert.Len(t, pkgs, 3) {
+			assert.Equal(t, uint16(138), pkgs[0].PacketIdentifier)
+			assert.Equal(t, byte(PtAppdataPacket), pkgs[1].PacketType)
+			assert.Equal(t, byte(PtResponsePacket), pkgs[2].PacketType)
+		}
+	}
+
+	// остаток дополняется следующими данными
+	rest := append(append([]byte{}, data[consumed:]...), egtsPkgPosDataBytes[15:]...)
+	pkgs, consumed, err = ParseAll(rest)
+	if assert.NoError(t, err) {
+		assert.Len(t, pkgs, 1)
+		assert.Equal(t, len(egtsPkgPosDataBytes), consumed)
+	}
+
+	// ошибка во втором пакете: неверная контрольная сумма заголовка
+	corrupted := append(append([]byte{}, egtsPkgPosDataBytes...), egtsPkgPosDataBytes...)
+	corrupted[len(egtsPkgPosDataBytes)+10] ^= 0xFF
+	pkgs, consumed, err = ParseAll(corrupted)
+	assert.Len(t, pkgs, 1)
+	assert.Equal(t, len(egtsPkgPosDataBytes), consumed)
+	if pe, ok := err.(*ParseError); assert.True(t, ok) {
+		assert.Equal(t, len(egtsPkgPosDataBytes)+10, pe.Offset)
+		assert.Equal(t, PcHeaderCrcError, ResultCode(err))
+	}
+}
@@ -0,0 +1,69 @@
+package egts
+
+import "sync"
+
+//Subrecord подзапись, которая сама сообщает код своего типа (SRT). Реализуется подзаписями, которые
+//регистрируются через RegisterSubrecord
+type Subrecord interface {
+	BinaryData
+	Type() byte
+}
+
+// subrecordFactories зарегистрированные типы подзаписей по коду SRT. Регистрация возможна во время разбора
+// пакетов в других горутинах, поэтому доступ защищен subrecordFactoriesMu
+var (
+	subrecordFactoriesMu sync.RWMutex
+	subrecordFactories   = map[byte]func() Subrecord{}
+)
+
+//RegisterSubrecord задает тип подзаписи для кода srt: при разборе записи для подзаписи с этим кодом
+//вызывается factory. Регистрация имеет приоритет над встроенными типами, nil отменяет ее. Безопасна для
+//вызова одновременно с разбором пакетов, изменение применяется к подзаписям, разбираемым после вызова
+func RegisterSubrecord(srt byte, factory func() Subrecord) {
+	subrecordFactoriesMu.Lock()
+	defer subrecordFactoriesMu.Unlock()
+
+	if factory == nil {
+		delete(subrecordFactories, srt)
+		return
+	}
+	subrecordFactories[srt] = factory
+}
+
+// subrecordFactory возвращает зарегистрированный тип подзаписи для кода srt
+func subrecordFactory(srt byte) (func() Subrecord, bool) {
+	subrecordFactoriesMu.RLock()
+	defer subrecordFactoriesMu.RUnlock()
+
+	factory, ok := subrecordFactories[srt]
+	return factory, ok
+}
+
+//RawSubrecord подзапись неизвестного или не реализованного библиотекой типа (например EGTS_SR_ACCEL_DATA),
+//содержимое которой сохраняется без разбора. Используется при разборе с Package.KeepUnknownSubrecords и
+//кодируется обратно без изменений, что позволяет пересылать пакеты, не разбирая их полностью
+type RawSubrecord struct {
+	SRT  byte   `json:"SRT"`
+	Data []byte `json:"SRD"`
+}
+
+//Type возвращает код типа подзаписи
+func (r *RawSubrecord) Type() byte {
+	return r.SRT
+}
+
+//Decode сохраняет байты подзаписи
+func (r *RawSubrecord) Decode(content []byte) error {
+	r.Data = append([]byte{}, content...)
+	return nil
+}
+
+//Encode возвращает сохраненные байты подзаписи
+func (r *RawSubrecord) Encode() ([]byte, error) {
+	return r.Data, nil
+}
+
+//Length получает длинну закодированной подзаписи
+func (r *RawSubrecord) Length() uint16 {
+	return uint16(len(r.Data))
+}
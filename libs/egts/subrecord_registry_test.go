@@ -0,0 +1,147 @@
+package egts
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+// testFuelSubrecord подзапись платформы с уровнем топлива в литрах
+type testFuelSubrecord struct {
+	Liters uint16
+}
+
+func (f *testFuelSubrecord) Type() byte { return 0xF0 }
+
+func (f *testFuelSubrecord) Decode(content []byte) error {
+	if len(content) != 2 {
+		return newParseError(0, "Некорректная длина подзаписи: %d", len(content))
+	}
+	f.Liters = binary.LittleEndian.Uint16(content)
+	return nil
+}
+
+func (f *testFuelSubrecord) Encode() ([]byte, error) {
+	result := make([]byte, 2)
+	binary.LittleEndian.PutUint16(result, f.Liters)
+	return result, nil
+}
+
+func (f *testFuelSubrecord) Length() uint16 { return 2 }
+
+func TestRegisterSubrecord(t *testing.T) {
+	pos := testEgtsSrPosData
+	rec := NewServiceDataRecord(1, 0, TeledataService, RecordDataSet{
+		{SubrecordData: &pos},
+		{SubrecordData: &testFuelSubrecord{Liters: 412}},
+	})
+	pkgBytes, err := NewAppdataPackage(1, ServiceDataSet{rec}).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// без регистрации тип неизвестен
+	_, err = (&Package{}).Decode(pkgBytes)
+	assert.Error(t, err)
+
+	RegisterSubrecord(0xF0, func() Subrecord { return &testFuelSubrecord{} })
+	defer RegisterSubrecord(0xF0, nil)
+
+	pkg := Package{}
+	if _, err = pkg.Decode(pkgBytes); assert.NoError(t, err) {
+		rds := (*pkg.ServicesFrameData.(*ServiceDataSet))[0].RecordDataSet
+		if assert.Len(t, rds, 2) {
+			assert.Equal(t, byte(0xF0), rds[1].SubrecordType)
+			assert.Equal(t, &testFuelSubrecord{Liters: 412}, rds[1].SubrecordData)
+		}
+	}
+}
+
+func TestRegisterSubrecord_Concurrent(t *testing.T) {
+	pkgBytes, err := NewAppdataPackage(1, ServiceDataSet{
+		NewServiceDataRecord(1, 0, TeledataService, RecordDataSet{{SubrecordData: &testFuelSubrecord{Liters: 1}}}),
+	}).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer RegisterSubrecord(0xF0, nil)
+
+	// регистрация во время разбора пакетов в других горутинах (проверяется go test -race)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = (&Package{KeepUnknownSubrecords: true}).Decode(pkgBytes)
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		RegisterSubrecord(0xF0, func() Subrecord { return &testFuelSubrecord{} })
+		RegisterSubrecord(0xF0, nil)
+	}
+	wg.Wait()
+}
+
+func TestPackage_KeepUnknownSubrecords(t *testing.T) {
+	pos := testEgtsSrPosData
+	rec := NewServiceDataRecord(1, 0, TeledataService, RecordDataSet{
+		{SubrecordData: &pos},
+		{SubrecordData: &RawSubrecord{SRT: 0xEE, Data: []byte{0x01, 0x02, 0x03}}},
+	})
+	pkgBytes, err := NewAppdataPackage(1, ServiceDataSet{rec}).Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	pkg := Package{KeepUnknownSubrecords: true}
+	if _, err = pkg.Decode(pkgBytes); assert.NoError(t, err) {
+		rds := (*pkg.ServicesFrameData.(*ServiceDataSet))[0].RecordDataSet
+		if assert.Len(t, rds, 2) {
+			assert.Equal(t, &RawSubrecord{SRT: 0xEE, Data: []byte{0x01, 0x02, 0x03}}, rds[1].SubrecordData)
+		}
+
+		reencoded, err := pkg.Encode()
+		if assert.NoError(t, err) {
+			assert.Equal(t, pkgBytes, reencoded)
+		}
+	}
+}
+
+func TestRawSubrecord_Passthrough(t *testing.T) {
+	rdsBytes := append(append([]byte{}, testRecordDataBytes...),
+		0x14, 0x07, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // EGTS_SR_ACCEL_DATA, не реализована
+		0xEE, 0x03, 0x00, 0xAA, 0xBB, 0xCC, // тип, неизвестный библиотеке
+	)
+	recBytes := append([]byte{0x00, 0x00, 0x05, 0x00, 0x00, 0x02, 0x02}, rdsBytes...)
+	binary.LittleEndian.PutUint16(recBytes, uint16(len(rdsBytes)))
+
+	pkg := NewAppdataPackage(9, nil)
+	pkg.ServicesFrameData = rawFrame(recBytes)
+	pkgBytes, err := pkg.Encode()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = (&Package{}).Decode(pkgBytes)
+	assert.Error(t, err)
+
+	decoded := Package{KeepUnknownSubrecords: true}
+	if _, err = decoded.Decode(pkgBytes); !assert.NoError(t, err) {
+		return
+	}
+	rds := (*decoded.ServicesFrameData.(*ServiceDataSet))[0].RecordDataSet
+	if assert.Len(t, rds, 3) {
+		assert.Equal(t, RecordData{SubrecordType: SrType20, SubrecordLength: 7,
+			SubrecordData: &RawSubrecord{SRT: SrType20, Data: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}}}, rds[1])
+		assert.Equal(t, RecordData{SubrecordType: 0xEE, SubrecordLength: 3,
+			SubrecordData: &RawSubrecord{SRT: 0xEE, Data: []byte{0xAA, 0xBB, 0xCC}}}, rds[2])
+	}
+
+	reencoded, err := decoded.Encode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, pkgBytes, reencoded)
+	}
+}
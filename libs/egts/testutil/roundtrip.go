@@ -0,0 +1,33 @@
+package testutil
+
+import (
+	"reflect"
+
+	"github.com/kuznetsovin/egts-protocol/libs/egts"
+	"github.com/stretchr/testify/assert"
+)
+
+//AssertRoundTrip кодирует подзапись, разбирает полученные байты в новый экземпляр того же типа и проверяет,
+//что результат совпадает с исходной подзаписью. Подзапись должна передаваться указателем на структуру
+func AssertRoundTrip(t assert.TestingT, sr egts.BinaryData) bool {
+	srType := reflect.TypeOf(sr)
+	if srType == nil || srType.Kind() != reflect.Ptr {
+		return assert.Fail(t, "подзапись должна передаваться указателем", "тип: %T", sr)
+	}
+
+	srBytes, err := sr.Encode()
+	if !assert.NoError(t, err, "ошибка кодирования подзаписи") {
+		return false
+	}
+
+	if !assert.Equal(t, int(sr.Length()), len(srBytes), "длина подзаписи не совпадает с закодированной") {
+		return false
+	}
+
+	decoded := reflect.New(srType.Elem()).Interface().(egts.BinaryData)
+	if !assert.NoError(t, decoded.Decode(srBytes), "ошибка разбора подзаписи") {
+		return false
+	}
+
+	return assert.Equal(t, sr, decoded)
+}
@@ -0,0 +1,46 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/kuznetsovin/egts-protocol/libs/egts"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingT struct {
+	failed bool
+}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.failed = true
+}
+
+// подзапись, которая теряет данные при разборе
+type lossySubrecord struct {
+	Value uint8
+}
+
+func (l *lossySubrecord) Decode(content []byte) error {
+	return nil
+}
+
+func (l *lossySubrecord) Encode() ([]byte, error) {
+	return []byte{l.Value}, nil
+}
+
+func (l *lossySubrecord) Length() uint16 {
+	return 1
+}
+
+func TestAssertRoundTrip(t *testing.T) {
+	assert.True(t, AssertRoundTrip(t, &egts.SrResultCode{ResultCode: 151}))
+	assert.True(t, AssertRoundTrip(t, &egts.SrAbsCntrData{CounterNumber: 110, CounterValue: 0x042A77}))
+
+	rt := &recordingT{}
+	assert.False(t, AssertRoundTrip(rt, &lossySubrecord{Value: 1}))
+	assert.True(t, rt.failed)
+
+	rt = &recordingT{}
+	assert.False(t, AssertRoundTrip(rt, nil))
+	assert.True(t, rt.failed)
+}
@@ -0,0 +1,46 @@
+package egts
+
+//VehicleState укрупненное состояние транспортного средства
+type VehicleState uint8
+
+const (
+	//StateParked стоянка: ТС не движется, зажигание выключено
+	StateParked VehicleState = iota
+	//StateIdling остановка: ТС не движется, зажигание включено
+	StateIdling
+	//StateDriving движение
+	StateDriving
+)
+
+//VehicleStateThresholds пороги для определения состояния ТС
+type VehicleStateThresholds struct {
+	// минимальная скорость в км/ч, начиная с которой ТС считается движущимся
+	MinDrivingSpeed uint16
+	// номер бита в DIN (0..7), к которому подключен датчик зажигания
+	IgnitionInput uint8
+}
+
+//DefaultVehicleStateThresholds пороги определения состояния ТС по умолчанию
+var DefaultVehicleStateThresholds = VehicleStateThresholds{
+	MinDrivingSpeed: 5,
+	IgnitionInput:   0,
+}
+
+//IgnitionOn признак включенного зажигания по состоянию дискретного входа input
+func (e *SrPosData) IgnitionOn(input uint8) bool {
+	return e.DigitalInputs>>(input&0x7)&0x1 == 1
+}
+
+//VehicleState определяет состояние ТС по отметке. ТС считается в движении, если установлен флаг MV и
+//скорость не ниже th.MinDrivingSpeed (это отсекает дрейф координат на стоянке). Иначе при включенном
+//зажигании ТС находится на остановке, при выключенном - на стоянке
+func (e *SrPosData) VehicleState(th VehicleStateThresholds) VehicleState {
+	if e.MV == "1" && e.Speed >= th.MinDrivingSpeed {
+		return StateDriving
+	}
+
+	if e.IgnitionOn(th.IgnitionInput) {
+		return StateIdling
+	}
+	return StateParked
+}
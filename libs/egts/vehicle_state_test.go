@@ -0,0 +1,29 @@
+package egts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSrPosData_VehicleState(t *testing.T) {
+	th := DefaultVehicleStateThresholds
+
+	parked := SrPosData{MV: "0", Speed: 0, DigitalInputs: 0x00}
+	assert.Equal(t, StateParked, parked.VehicleState(th))
+
+	idling := SrPosData{MV: "0", Speed: 0, DigitalInputs: 0x01}
+	assert.Equal(t, StateIdling, idling.VehicleState(th))
+
+	driving := SrPosData{MV: "1", Speed: 60, DigitalInputs: 0x01}
+	assert.Equal(t, StateDriving, driving.VehicleState(th))
+
+	// дрейф координат на низкой скорости не считается движением
+	drift := SrPosData{MV: "1", Speed: 3, DigitalInputs: 0x01}
+	assert.Equal(t, StateIdling, drift.VehicleState(th))
+	assert.Equal(t, StateDriving, drift.VehicleState(VehicleStateThresholds{MinDrivingSpeed: 2}))
+
+	// зажигание на другом входе
+	ignitionOn3 := SrPosData{MV: "0", DigitalInputs: 0x08}
+	assert.Equal(t, StateParked, ignitionOn3.VehicleState(th))
+	assert.Equal(t, StateIdling, ignitionOn3.VehicleState(VehicleStateThresholds{MinDrivingSpeed: 5, IgnitionInput: 3}))
+}